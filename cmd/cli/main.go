@@ -46,18 +46,23 @@ func main() {
 		fmt.Printf("clamping timeout to refresh %s\n", refreshDur.String())
 	}
 
-	nameSet := make(map[string]struct{})
-	lineSource := []data.Line{data.EW_1, data.NS_1, data.CG_1}
-
-	for _, l := range lineSource {
-		for i := range l {
-			nameSet[l[i].Name] = struct{}{}
+	names := data.GetNames()
+
+	// stations given as arguments are shown on their own, by any name the index knows
+	var stations []*data.Node
+	for _, arg := range flag.Args() {
+		node, err := data.GetIndex().Lookup(arg)
+		if err != nil {
+			fmt.Printf("%s: %v\n", arg, err)
+			os.Exit(1)
 		}
+		stations = append(stations, node)
 	}
-
-	var names []string
-	for k := range nameSet {
-		names = append(names, k)
+	if len(stations) > 0 {
+		names = names[:0]
+		for _, node := range stations {
+			names = append(names, node.Name)
+		}
 	}
 
 	timer := time.Tick(refreshDur)
@@ -75,25 +80,30 @@ func main() {
 				return
 			}
 
-			positions := map[string]model.Position{
-				"ns1": smrt.ToModel(results, data.NS_1).ToPosition(),
-				"ns2": smrt.ToModel(results, data.NS_2).ToPosition(),
-				"ew1": smrt.ToModel(results, data.EW_1).ToPosition(),
-				"ew2": smrt.ToModel(results, data.EW_2).ToPosition(),
-				"cg1": smrt.ToModel(results, data.CG_1).ToPosition(),
-				"cg2": smrt.ToModel(results, data.CG_2).ToPosition(),
+			if len(stations) > 0 {
+				fmt.Print("\033[H\033[2J") // clear terminal
+				for _, node := range stations {
+					fmt.Println(formatStation(node, results[node.Name]))
+				}
+				return
+			}
+
+			positions := make(map[string]model.Position)
+			for _, l := range data.GetLines() {
+				positions[l.Name] = smrt.ToModel(results, l.Line).ToPositionFor(l.Line)
 			}
 
 			fmt.Print("\033[H\033[2J") // clear terminal
 
-			fmt.Println(formatPair(data.NS_1, positions["ns1"].ToString(), positions["ns2"].Reverse().ToString()))
-			fmt.Println(formatPair(data.EW_1, positions["ew1"].ToString(), positions["ew2"].Reverse().ToString()))
-			fmt.Println(formatPair(data.CG_1, positions["cg1"].ToString(), positions["cg2"].Reverse().ToString()))
+			for _, pair := range [][2]string{{"ns1", "ns2"}, {"ew1", "ew2"}, {"cg1", "cg2"}} {
+				l, ok := data.GetLine(pair[0])
+				if !ok {
+					continue
+				}
+				fmt.Println(formatPair(l, positions[pair[0]].ToString(), positions[pair[1]].Reverse().ToString()))
+			}
 
 			// pack all
-			for k, v := range positions {
-				positions[k] = v[:len(v)-1]
-			}
 
 			packed, err := model.PackBoardV1(positions)
 			if err != nil {
@@ -131,3 +141,21 @@ func formatPair(dl data.Line, forward string, reverse string) string {
 
 	return sb.String()
 }
+
+func formatStation(node *data.Node, results smrt.Result) string {
+	var sb strings.Builder
+
+	sb.WriteString(node.Name)
+	for _, s := range node.Stops {
+		id := s.AsStation().PlatformID()
+		fmt.Fprintf(&sb, "\n  %s %s", s.Line, id)
+		for _, r := range results {
+			if r.PlatformID == id {
+				fmt.Fprintf(&sb, " %s: %s, %s", r.NextTrainDestination, r.NextTrainArr, r.SubseqTrainArr)
+				break
+			}
+		}
+	}
+
+	return sb.String()
+}
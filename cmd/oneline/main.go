@@ -9,9 +9,10 @@ import (
 )
 
 func main() {
-	names := make([]string, len(data.NS_1))
-	for i := range data.NS_1 {
-		names[i] = data.NS_1[i].PlatformID()
+	ns1, _ := data.GetLine("ns1")
+	names := make([]string, len(ns1))
+	for i := range ns1 {
+		names[i] = ns1[i].PlatformID()
 	}
 
 	results, err := smrt.GetNPlatform(context.Background(), len(names), 100, names...)
@@ -19,13 +20,13 @@ func main() {
 		panic(err)
 	}
 
-	modelLine := smrt.ToModelPlatform(results, data.NS_1)
-	if len(modelLine) != len(data.NS_1) {
-		panic(fmt.Sprintf("dim mismatch %d %d", len(modelLine), len(data.NS_1)))
+	modelLine := smrt.ToModelPlatform(results, ns1)
+	if len(modelLine) != len(ns1) {
+		panic(fmt.Sprintf("dim mismatch %d %d", len(modelLine), len(ns1)))
 	}
 
 	for i := range modelLine {
-		fmt.Printf("%s: %+v\n", data.NS_1[i].Name, modelLine[i])
-		fmt.Printf(">> [%v]\n\n", results[data.NS_1[i].Name])
+		fmt.Printf("%s: %+v\n", ns1[i].Name, modelLine[i])
+		fmt.Printf(">> [%v]\n\n", results[ns1[i].Name])
 	}
 }
@@ -15,7 +15,7 @@ import (
 var (
 	replay = flag.Bool("replay", false, "")
 
-	l = data.EW_1
+	l, _ = data.GetLine("ew1")
 )
 
 func main() {
@@ -63,14 +63,14 @@ func main() {
 		panic(fmt.Sprintf("dim mismatch %d %d", len(modelLine), len(l)))
 	}
 
-	pos := modelLine.ToPosition()
+	pos := modelLine.ToPositionFor(l)
 
 	for i := range pos {
 		fmt.Printf("%t\t\t", pos[i])
 		if i%2 == 0 {
 			fmt.Printf("%s: %+v", l[i/2].Name, modelLine[i/2])
 		}
-		//fmt.Printf(">> [%v]\n\n", results[data.NS_1[i].Name])
+		//fmt.Printf(">> [%v]\n\n", results[l[i].Name])
 		fmt.Println()
 	}
 }
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/recorder"
+)
+
+const (
+	envConfig    = "RECORDER_CONFIG" // optional JSON config file
+	envStore     = "STORE"           // mysql (default) or sqlite
+	envDsn       = "DSN"             // for sqlite, the database file
+	envArchive   = "ARCHIVE"         // if set, raw results are archived in this directory
+	envSpool     = "SPOOL"           // snapshots that couldn't be saved wait here
+	envLog       = "RECORDER_LOG"
+	envLines     = "RECORDER_LINES" // comma separated line names
+	envInterval  = "RECORDER_INTERVAL"
+	envTimeout   = "RECORDER_TIMEOUT"
+	envRound     = "RECORDER_ROUND"
+	envWorkers   = "RECORDER_WORKERS"
+	envTries     = "RECORDER_TRIES"
+	envLinesFile = "LINES_FILE" // replaces the built in line data
+
+	logStderr = "-"
+)
+
+// config is everything the recorder can be told. Each value comes from the first of these
+// that has it: a flag, the environment, the config file, the default.
+type config struct {
+	// Time between polls
+	Interval duration `json:"interval"`
+	// Deadline for a whole poll, including saving
+	Timeout duration `json:"timeout"`
+	// Polls start on a multiple of this, 0 to start straight away
+	Round duration `json:"round"`
+	// Concurrent requests to smrt, 0 for one per station
+	Workers int `json:"workers"`
+	// Most requests to smrt for one station in a poll, including retries
+	Tries int `json:"tries"`
+	// Line names to record, empty for every line
+	Lines []string `json:"lines"`
+	// Line definitions to use instead of the built in ones, see data.Load
+	LinesFile string `json:"lines_file"`
+
+	Store   string `json:"store"`
+	DSN     string `json:"dsn"`
+	Archive string `json:"archive"`
+	Spool   string `json:"spool"`
+	// Log file, rotated on startup. "-" logs to stderr instead.
+	Log string `json:"log"`
+
+	// Only from flags
+	Once bool   `json:"-"`
+	path string `json:"-"`
+}
+
+func defaultConfig() config {
+	return config{
+		Interval: duration{30 * time.Second},
+		Timeout:  duration{30 * time.Second},
+		Round:    duration{30 * time.Second},
+		Workers:  0,
+		Tries:    10,
+		Store:    recorder.BackendMySQL,
+		Spool:    "spool",
+		Log:      "recorder.log",
+	}
+}
+
+// bind adds flags for every config value to fs
+func (c *config) bind(fs *flag.FlagSet) {
+	fs.StringVar(&c.path, "config", c.path, "JSON config file (env "+envConfig+")")
+	fs.Var(&c.Interval, "interval", "time between polls")
+	fs.Var(&c.Timeout, "timeout", "deadline for each poll")
+	fs.Var(&c.Round, "round", "start polls on a multiple of this, 0 to start straight away")
+	fs.IntVar(&c.Workers, "workers", c.Workers, "concurrent requests, 0 for one per station")
+	fs.IntVar(&c.Tries, "tries", c.Tries, "most requests for one station per poll, including retries")
+	fs.Var((*listValue)(&c.Lines), "lines", "comma separated lines to record (default all)")
+	fs.StringVar(&c.LinesFile, "lines-file", c.LinesFile, "line definitions to use instead of the built in ones")
+	fs.StringVar(&c.Store, "store", c.Store, "store backend, mysql or sqlite")
+	fs.StringVar(&c.DSN, "dsn", c.DSN, "store DSN, for sqlite the database file")
+	fs.StringVar(&c.Archive, "archive", c.Archive, "archive raw results in this directory")
+	fs.StringVar(&c.Spool, "spool", c.Spool, "directory for snapshots that couldn't be saved")
+	fs.StringVar(&c.Log, "log", c.Log, "log file, - for stderr")
+	fs.BoolVar(&c.Once, "once", c.Once, "poll once and exit, for cron")
+}
+
+// loadConfig parses args with fs, which may already have flags of its own, and works out the config
+func loadConfig(fs *flag.FlagSet, args []string) (config, error) {
+	flagged := defaultConfig()
+	flagged.bind(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return config{}, err
+	}
+
+	set := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+
+	c := defaultConfig()
+	path := flagged.path
+	if path == "" {
+		path = os.Getenv(envConfig)
+	}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return c, err
+		}
+		err = json.Unmarshal(b, &c)
+		if err != nil {
+			return c, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	err = c.loadEnv()
+	if err != nil {
+		return c, err
+	}
+
+	// flags win, apply them again on top
+	over := flag.NewFlagSet("", flag.ContinueOnError)
+	c.bind(over)
+	for name, value := range set {
+		if over.Lookup(name) == nil {
+			continue // belongs to the subcommand
+		}
+		err = over.Set(name, value)
+		if err != nil {
+			return c, err
+		}
+	}
+
+	if c.LinesFile != "" {
+		err = data.Load(c.LinesFile)
+		if err != nil {
+			return c, err
+		}
+	}
+
+	return c, c.validate()
+}
+
+func (c *config) loadEnv() error {
+	strs := map[string]*string{
+		envStore:     &c.Store,
+		envDsn:       &c.DSN,
+		envArchive:   &c.Archive,
+		envSpool:     &c.Spool,
+		envLog:       &c.Log,
+		envLinesFile: &c.LinesFile,
+	}
+	for env, p := range strs {
+		if v, ok := os.LookupEnv(env); ok {
+			*p = v
+		}
+	}
+
+	durations := map[string]*duration{
+		envInterval: &c.Interval,
+		envTimeout:  &c.Timeout,
+		envRound:    &c.Round,
+	}
+	for env, p := range durations {
+		if v, ok := os.LookupEnv(env); ok {
+			err := p.Set(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", env, err)
+			}
+		}
+	}
+
+	ints := map[string]*int{
+		envWorkers: &c.Workers,
+		envTries:   &c.Tries,
+	}
+	for env, p := range ints {
+		if v, ok := os.LookupEnv(env); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", env, err)
+			}
+			*p = n
+		}
+	}
+
+	if v, ok := os.LookupEnv(envLines); ok {
+		_ = (*listValue)(&c.Lines).Set(v)
+	}
+
+	return nil
+}
+
+func (c *config) validate() error {
+	if c.Interval.Duration <= 0 {
+		return errors.New("interval must be positive")
+	}
+	if c.Timeout.Duration <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	if c.Round.Duration < 0 {
+		return errors.New("round can't be negative")
+	}
+	if c.Workers < 0 || c.Tries <= 0 {
+		return errors.New("workers can't be negative and tries must be positive")
+	}
+
+	known := make(map[string]bool)
+	for _, l := range data.GetLines() {
+		known[l.Name] = true
+	}
+	for _, name := range c.Lines {
+		if !known[name] {
+			return fmt.Errorf("unknown line %q", name)
+		}
+	}
+
+	return nil
+}
+
+// records reports whether the line is one of the ones to record
+func (c *config) records(line string) bool {
+	if len(c.Lines) == 0 {
+		return true
+	}
+	for _, name := range c.Lines {
+		if name == line {
+			return true
+		}
+	}
+	return false
+}
+
+// stations lists every station to poll for the lines being recorded
+func (c *config) stations() []string {
+	set := make(map[string]struct{})
+	var out []string
+	for _, l := range data.GetLines() {
+		if !c.records(l.Name) {
+			continue
+		}
+		for _, s := range l.Line {
+			if _, ok := set[s.Name]; !ok {
+				set[s.Name] = struct{}{}
+				out = append(out, s.Name)
+			}
+		}
+	}
+	return out
+}
+
+// duration is a time.Duration that is written as "30s" in flags, env and JSON
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+	return d.Set(s)
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// listValue is a comma separated flag
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(s string) error {
+	*l = nil
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*l = append(*l, part)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recorder.json")
+	err := os.WriteFile(path, []byte(`{"interval":"1m","tries":5,"lines":["ew1"],"store":"sqlite","dsn":"file.db"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv(envTries, "7")
+	defer os.Unsetenv(envTries)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	from := fs.String("from", "", "")
+	c, err := loadConfig(fs, []string{"-config", path, "-from", "x", "-lines", "ns1,ns2", "-once"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *from != "x" {
+		t.Errorf("subcommand flag lost")
+	}
+	if c.Interval.Duration != time.Minute || c.Store != "sqlite" || c.DSN != "file.db" {
+		t.Errorf("config file not applied: %+v", c)
+	}
+	if c.Tries != 7 {
+		t.Errorf("env should beat the file, got %d tries", c.Tries)
+	}
+	if !reflect.DeepEqual(c.Lines, []string{"ns1", "ns2"}) || !c.Once {
+		t.Errorf("flags should beat everything, got %+v", c)
+	}
+	if c.Timeout.Duration != 30*time.Second || c.Spool != "spool" {
+		t.Errorf("defaults not kept: %+v", c)
+	}
+
+	_, err = loadConfig(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-lines", "nope"})
+	if err == nil {
+		t.Error("expected unknown line to fail")
+	}
+}
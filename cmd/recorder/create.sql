@@ -1,19 +1,11 @@
-use `traintracker`;
+# One-off setup of the recorder database and user.
+# Tables are created and migrated by the recorder itself on startup, see recorder/migrations.
+# Substitute a real password before running.
 
-create table recorded_position (
-    id int primary key auto_increment,
-    day_of_week tinyint not null,
-    seconds_of_day int not null,
-    time timestamp not null,
-    name varchar(10) not null,
-    line_repr varchar(200) not null,
-    index day_second_name (day_of_week, seconds_of_day, name),
-    unique index time_name (time, name)
-);
+create database if not exists `traintracker`;
 
 create user if not exists 'traintracker_recorder'@'%'
-    identified by '6d67360fa5c2fdf4f41d469ba322ac28';
+    identified by '<password>';
 
-grant select, insert, update, delete on traintracker.recorded_position to 'traintracker_recorder'@'%';
-
-# insert into recorded_position (day_of_week, seconds_of_day, time, name, line_repr) values (?,?,?,?,?)
\ No newline at end of file
+# create, alter and index are needed for migrations
+grant select, insert, update, delete, create, alter, index on traintracker.* to 'traintracker_recorder'@'%';
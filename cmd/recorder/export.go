@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/recorder"
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+const (
+	exportRecorded = "recorded" // recorded_position rows, one column per segment
+	exportArchive  = "archive"  // archived raw arrivals, next and subsequent minutes per station
+
+	exportPage = 5000
+)
+
+// export writes recordings in a time range to one file per line, <line>.<format>, for analysis.
+func export(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fromStr := fs.String("from", "", "start time, RFC 3339 (required)")
+	toStr := fs.String("to", "", "end time, RFC 3339 (default now)")
+	format := fs.String("format", recorder.FormatCSV, "csv or parquet")
+	source := fs.String("source", exportRecorded, "recorded positions or archive arrivals")
+	out := fs.String("o", ".", "output directory")
+	c := mustLoadConfig(fs, args)
+
+	from, err := time.Parse(time.RFC3339, *fromStr)
+	if err != nil {
+		fmt.Printf("invalid from: %v\n", err)
+		os.Exit(1)
+	}
+
+	to := time.Now()
+	if *toStr != "" {
+		to, err = time.Parse(time.RFC3339, *toStr)
+		if err != nil {
+			fmt.Printf("invalid to: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *source != exportRecorded && *source != exportArchive {
+		fmt.Printf("unknown source %q\n", *source)
+		os.Exit(1)
+	}
+
+	var lines []data.LineNameDataPair
+	for _, l := range data.GetLines() {
+		if c.records(l.Name) {
+			lines = append(lines, l)
+		}
+	}
+
+	err = os.MkdirAll(*out, 0755)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	var files []*os.File
+	exporters := make(map[string]recorder.Exporter)
+	for _, l := range lines {
+		columns := l.Line.SegmentLabels()
+		if *source == exportArchive {
+			columns = arrivalLabels(l.Line)
+		}
+
+		f, err := os.Create(filepath.Join(*out, l.Name+"."+*format))
+		if err != nil {
+			panic(err)
+		}
+		files = append(files, f)
+
+		exporters[l.Name], err = recorder.NewExporter(*format, f, columns)
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var count int
+	switch *source {
+	case exportRecorded:
+		store := openStore(c)
+		defer store.Close()
+		count, err = exportRecordedRows(ctx, store, lines, exporters, from, to)
+	case exportArchive:
+		if c.Archive == "" {
+			fmt.Printf("archive is not set, use -archive or %s\n", envArchive)
+			os.Exit(1)
+		}
+		count, err = exportArchived(ctx, c.Archive, lines, exporters, from, to)
+	}
+
+	for name, e := range exporters {
+		if err2 := e.Close(); err2 != nil && err == nil {
+			err = fmt.Errorf("%s: %w", name, err2)
+		}
+	}
+	for _, f := range files {
+		if err2 := f.Close(); err2 != nil && err == nil {
+			err = err2
+		}
+	}
+
+	fmt.Printf("exported %d rows\n", count)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// exportRecordedRows pages through the store a line at a time, so the range can be any size
+func exportRecordedRows(ctx context.Context, store recorder.Store, lines []data.LineNameDataPair,
+	exporters map[string]recorder.Exporter, from, to time.Time) (int, error) {
+
+	count := 0
+	for _, l := range lines {
+		e := exporters[l.Name]
+		width := len(l.Line)*2 - 1
+		skipped := 0
+
+		for next := from; next.Before(to); {
+			rows, err := store.QueryRange(ctx, l.Name, next, to, exportPage)
+			if err != nil {
+				return count, err
+			}
+
+			for _, row := range rows {
+				p, err := model.NewPositionFromString(row.Repr)
+				if err != nil || len(p) != width {
+					// recorded before the line changed
+					skipped++
+					continue
+				}
+				values := make([]int, len(p))
+				for i := range p {
+					if p[i] {
+						values[i] = 1
+					}
+				}
+
+				err = e.Write(recorder.ExportRecord{
+					Time:   row.Time,
+					Line:   l.Name,
+					Source: row.Source,
+					Values: values,
+				})
+				if err != nil {
+					return count, err
+				}
+				count++
+			}
+
+			if len(rows) < exportPage {
+				break
+			}
+			// times are stored to the millisecond at best
+			next = rows[len(rows)-1].Time.Add(time.Millisecond)
+		}
+
+		if skipped > 0 {
+			fmt.Printf("%s: skipped %d rows that don't fit the current line\n", l.Name, skipped)
+		}
+	}
+	return count, nil
+}
+
+func exportArchived(ctx context.Context, dir string, lines []data.LineNameDataPair,
+	exporters map[string]recorder.Exporter, from, to time.Time) (int, error) {
+
+	archive, err := recorder.NewArchive(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = archive.Range(from, to, func(t time.Time, raw map[string]smrt.Result) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		for _, l := range lines {
+			ml := smrt.ToModel(raw, l.Line)
+			values := make([]int, 0, len(ml)*2)
+			for _, p := range ml {
+				values = append(values, p.Next, p.Subseq)
+			}
+
+			err := exporters[l.Name].Write(recorder.ExportRecord{
+				Time:   t,
+				Line:   l.Name,
+				Source: exportArchive,
+				Values: values,
+			})
+			if err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// arrivalLabels names the columns of an archive export, eg "EW1_next", "EW1_subseq"
+func arrivalLabels(l data.Line) []string {
+	out := make([]string, 0, len(l)*2)
+	for _, s := range l {
+		out = append(out, s.Code+"_next", s.Code+"_subseq")
+	}
+	return out
+}
@@ -2,110 +2,199 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
-	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/recorder"
 	"go.lepak.sg/mrtracker-backend/smrt"
 )
 
-const (
-	pollInterval = 30 * time.Second
-	pollTimeout  = 30 * time.Second
-	timeRound    = 30
-	logName      = "recorder.log"
-	envDsn       = "DSN"
-	saveCommand  = "insert into recorded_position (day_of_week, seconds_of_day, time, name, line_repr) values (?,?,?,?,?)"
-)
-
+// Usage:
+//
+//	recorder [-once] [flags]                     record until interrupted
+//	recorder recompute -from T -to T [flags]     regenerate recorded positions from the archive
+//	recorder retain -keep D -horizon D [flags]   downsample and delete old recordings
+//	recorder export -from T -to T [flags]        write recordings to CSV or Parquet files
+//	recorder runtimes -from T -to T [flags]      learn run times between stations from arrivals
+//
+// Run with -h for the flags. Each one can also be set in the environment or a JSON config file,
+// see config.go.
 func main() {
-	// create name list from line data
-	names := data.GetNames()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "recompute":
+			recompute(os.Args[2:])
+			return
+		case "retain":
+			retain(os.Args[2:])
+			return
+		case "export":
+			export(os.Args[2:])
+			return
+		case "runtimes":
+			runTimes(os.Args[2:])
+			return
+		}
+	}
+	err := record(os.Args[1:])
+	if err != nil {
+		os.Exit(1)
+	}
+}
 
-	// connect to recorder db
-	dsn := os.Getenv(envDsn)
-	if dsn == "" {
+func mustLoadConfig(fs *flag.FlagSet, args []string) config {
+	c, err := loadConfig(fs, args)
+	if err != nil {
+		fmt.Printf("config: %v\n", err)
+		os.Exit(2)
+	}
+	return c
+}
+
+func openStore(c config) recorder.Store {
+	if c.DSN == "" {
 		panic("where is dsn?")
 	}
-	db, err := sql.Open("mysql", dsn)
+	store, err := recorder.Open(c.Store, c.DSN)
 	if err != nil {
 		panic(err)
 	}
-	err = db.Ping()
+	return store
+}
+
+// openLog points the log at the configured destination. An existing log file is rotated first.
+func openLog(name string) (*os.File, error) {
+	if name == logStderr {
+		return nil, nil
+	}
+
+	logStat, err := os.Stat(name)
+	if err == nil {
+		newname := fmt.Sprintf("%s.%s", name, logStat.ModTime().Format("060102.150405"))
+		err = os.Rename(name, newname)
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	log.SetOutput(f)
+	return f, nil
+}
+
+// record polls until interrupted, or just once with -once. It only returns an error if the
+// single poll of -once failed, the error is already logged.
+func record(args []string) error {
+	c := mustLoadConfig(flag.NewFlagSet("recorder", flag.ExitOnError), args)
+
+	// create name list from line data
+	names := c.stations()
 
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(10)
-	db.SetConnMaxLifetime(1 * time.Hour)
-	db.SetConnMaxIdleTime(1 * time.Hour)
+	// connect to recorder db
+	store := openStore(c)
 	defer func() {
-		err = db.Close()
+		err := store.Close()
 		if err != nil {
-			log.Printf("error closing db: %v", err)
+			log.Printf("error closing store: %v", err)
 		}
 	}()
 
-	// rotate old log file
-	logStat, err := os.Stat(logName)
-	if err == nil {
-		newname := fmt.Sprintf("%s.%s", logName, logStat.ModTime().Format("060102.150405"))
-		err = os.Rename(logName, newname)
+	migrated, err := store.Migrate(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	if migrated > 0 {
+		fmt.Printf("applied %d migrations\n", migrated)
+	}
+
+	spool, err := recorder.NewSpool(c.Spool)
+	if err != nil {
+		panic(err)
+	}
+
+	var archive *recorder.Archive
+	if c.Archive != "" {
+		archive, err = recorder.NewArchive(c.Archive)
 		if err != nil {
 			panic(err)
 		}
-	} else if !os.IsNotExist(err) {
-		panic(err)
 	}
 
-	f, err := os.OpenFile(logName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	f, err := openLog(c.Log)
 	if err != nil {
 		panic(err)
 	}
-	defer f.Close()
-	log.SetOutput(f)
+	if f != nil {
+		defer f.Close()
+	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
-	// round time to a nice number - multiple of 30s
-	delta := timeRound - (time.Now().Second() % timeRound)
-	<-time.After(time.Duration(delta) * time.Second)
-	tick := time.Tick(pollInterval)
-	fmt.Println("delay over, starting...")
+	if !c.Once && c.Round.Duration > 0 {
+		// round time to a nice number
+		now := time.Now()
+		<-time.After(now.Truncate(c.Round.Duration).Add(c.Round.Duration).Sub(now))
+		fmt.Println("delay over, starting...")
+	}
+	tick := time.NewTicker(c.Interval.Duration)
+	defer tick.Stop()
 
 	running := true
 	for running {
 		err = func() error {
-			pollCtx, pollCancel := context.WithTimeout(ctx, pollTimeout)
+			pollCtx, pollCancel := context.WithTimeout(ctx, c.Timeout.Duration)
 			defer pollCancel()
 
 			now := time.Now()
 
-			results, _, err := smrt.GetN(pollCtx, 0, 10, names...)
+			results, tries, err := smrt.GetN(pollCtx, c.Workers, c.Tries, names...)
 			if err != nil {
 				return err
 			}
 
-			lines := map[string]string{
-				"ns1": smrt.ToModel(results, data.NS_1).ToPosition().ToString(),
-				"ns2": smrt.ToModel(results, data.NS_2).ToPosition().ToString(),
-				"ew1": smrt.ToModel(results, data.EW_1).ToPosition().ToString(),
-				"ew2": smrt.ToModel(results, data.EW_2).ToPosition().ToString(),
-				"cg1": smrt.ToModel(results, data.CG_1).ToPosition().ToString(),
-				"cg2": smrt.ToModel(results, data.CG_2).ToPosition().ToString(),
+			if archive != nil {
+				// keep going even if this fails, the positions are more important
+				err = archive.Put(now, results)
+				if err != nil {
+					log.Printf("error archiving: %v", err)
+				}
 			}
 
-			err = save(pollCtx, db, now, lines)
+			snap := recorder.NewSnapshot(now, results)
+			snap.Tries = int(tries)
+			for name := range snap.Lines {
+				if !c.records(name) {
+					delete(snap.Lines, name)
+					delete(snap.Arrivals, name)
+				}
+			}
+			err = store.Save(pollCtx, snap)
 			if err != nil {
+				if err2 := spool.Push(snap); err2 != nil {
+					log.Printf("error spooling, snapshot lost: %v", err2)
+				}
 				return err
 			}
+
+			// the store is reachable again, catch up on anything that was missed.
+			// whatever doesn't fit in this tick is done in the next one
+			n, err := spool.Replay(pollCtx, store)
+			if n > 0 {
+				log.Printf("replayed %d spooled snapshots", n)
+			}
+			if err != nil {
+				return fmt.Errorf("replaying spool: %w", err)
+			}
 			return nil
 		}()
 
@@ -113,30 +202,14 @@ func main() {
 			log.Printf("error: %v", err)
 		}
 
+		if c.Once {
+			return err
+		}
+
 		select {
 		case <-ctx.Done():
 			running = false
-		case <-tick:
-		}
-	}
-}
-
-func save(ctx context.Context, db *sql.DB, now time.Time, lines map[string]string) error {
-	stmt, err := db.PrepareContext(ctx, saveCommand)
-	if err != nil {
-		return err
-	}
-	defer func(stmt *sql.Stmt) {
-		_ = stmt.Close()
-	}(stmt)
-
-	dayOfWeek := int(now.Weekday())
-	secondsOfDay := now.Hour()*3600 + now.Minute()*60 + now.Second()
-
-	for name, linerepr := range lines {
-		_, err := stmt.ExecContext(ctx, dayOfWeek, secondsOfDay, now, name, linerepr)
-		if err != nil {
-			return err
+		case <-tick.C:
 		}
 	}
 	return nil
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/recorder"
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+// recompute regenerates the recorded positions in a time range from the archived raw results,
+// using the current inference code. Existing rows are overwritten.
+func recompute(args []string) {
+	fs := flag.NewFlagSet("recompute", flag.ExitOnError)
+	fromStr := fs.String("from", "", "start time, RFC 3339 (required)")
+	toStr := fs.String("to", "", "end time, RFC 3339 (default now)")
+	dryRun := fs.Bool("n", false, "dry run, only print what would be written")
+	c := mustLoadConfig(fs, args)
+
+	dir := c.Archive
+	if dir == "" {
+		fmt.Printf("archive is not set, use -archive or %s\n", envArchive)
+		os.Exit(1)
+	}
+
+	from, err := time.Parse(time.RFC3339, *fromStr)
+	if err != nil {
+		fmt.Printf("invalid from: %v\n", err)
+		os.Exit(1)
+	}
+
+	to := time.Now()
+	if *toStr != "" {
+		to, err = time.Parse(time.RFC3339, *toStr)
+		if err != nil {
+			fmt.Printf("invalid to: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	archive, err := recorder.NewArchive(dir)
+	if err != nil {
+		panic(err)
+	}
+
+	var store recorder.Store
+	if !*dryRun {
+		store = openStore(c)
+		defer store.Close()
+
+		_, err = store.Migrate(context.Background())
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	count := 0
+	err = archive.Range(from, to, func(t time.Time, raw map[string]smrt.Result) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// the archive only knows the instant, the slot columns are in local time like when recording
+		snap := recorder.NewSnapshot(t.Local(), raw)
+		snap.Source = recorder.SourceRecompute
+		count++
+
+		if store == nil {
+			fmt.Println(snap.Time.Format(time.RFC3339))
+			for name, repr := range snap.Lines {
+				fmt.Printf("  %s %s\n", name, repr)
+			}
+			return nil
+		}
+		return store.Replace(ctx, snap)
+	})
+
+	fmt.Printf("recomputed %d ticks\n", count)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/recorder"
+)
+
+// retain folds old recordings into per-slot aggregates and deletes the oldest raw rows.
+// Meant to be run from cron, it can be interrupted and picks up where it left off.
+func retain(args []string) {
+	fs := flag.NewFlagSet("retain", flag.ExitOnError)
+	keepDays := fs.Int("keep", 30, "days of full resolution rows to keep")
+	horizonDays := fs.Int("horizon", 365, "days after which raw rows are deleted")
+	slot := fs.Duration("slot", 5*time.Minute, "aggregate slot width, can't change once set")
+	c := mustLoadConfig(fs, args)
+
+	store := openStore(c)
+	defer store.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	_, err := store.Migrate(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	stats, err := store.Retain(ctx, recorder.RetentionConfig{
+		KeepRaw: time.Duration(*keepDays) * 24 * time.Hour,
+		Horizon: time.Duration(*horizonDays) * 24 * time.Hour,
+		Slot:    *slot,
+	})
+	fmt.Printf("aggregated %d rows over %d days, deleted %d rows\n",
+		stats.RowsAggregated, stats.DaysAggregated, stats.RowsDeleted)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/recorder"
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+const (
+	runTimesArchive  = "archive"  // archived raw results
+	runTimesRecorded = "recorded" // the arrivals column of recorded_position
+)
+
+// runTimes learns the run and dwell times between stations from the arrivals recorded in a time
+// range, and writes them with the line data in the lines.json format, to be loaded with
+// -lines-file or LINES_FILE. Run times already in the line data are kept unless learned again.
+func runTimes(args []string) {
+	fs := flag.NewFlagSet("runtimes", flag.ExitOnError)
+	fromStr := fs.String("from", "", "start time, RFC 3339 (required)")
+	toStr := fs.String("to", "", "end time, RFC 3339 (default now)")
+	source := fs.String("source", runTimesArchive, "archive raw results or recorded arrivals")
+	minRuns := fs.Int("min", 10, "runs needed to estimate a segment")
+	out := fs.String("o", "lines.json", "output file")
+	c := mustLoadConfig(fs, args)
+
+	from, err := time.Parse(time.RFC3339, *fromStr)
+	if err != nil {
+		fmt.Printf("invalid from: %v\n", err)
+		os.Exit(1)
+	}
+
+	to := time.Now()
+	if *toStr != "" {
+		to, err = time.Parse(time.RFC3339, *toStr)
+		if err != nil {
+			fmt.Printf("invalid to: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *source != runTimesArchive && *source != runTimesRecorded {
+		fmt.Printf("unknown source %q\n", *source)
+		os.Exit(1)
+	}
+
+	lines, tracks, existing, err := currentLineData(c.LinesFile)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var learnFrom []data.LineNameDataPair
+	for _, l := range lines {
+		if c.records(l.Name) {
+			learnFrom = append(learnFrom, l)
+		}
+	}
+	learner := recorder.NewRunTimeLearner(learnFrom)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	var count int
+	switch *source {
+	case runTimesArchive:
+		if c.Archive == "" {
+			fmt.Printf("archive is not set, use -archive or %s\n", envArchive)
+			os.Exit(1)
+		}
+		count, err = learnArchived(ctx, c.Archive, learnFrom, learner, from, to)
+	case runTimesRecorded:
+		store := openStore(c)
+		defer store.Close()
+		count, err = learnRecorded(ctx, store, learnFrom, learner, from, to)
+	}
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	learned := learner.RunTimes(*minRuns)
+	for _, r := range learned {
+		fmt.Printf("%s-%s: run %ds, dwell %ds from %d runs\n", r.From, r.To, r.Run, r.Dwell,
+			learner.Samples(r.From, r.To))
+	}
+	fmt.Printf("learned %d run times from %d records\n", len(learned), count)
+
+	encoded := data.EncodeLineData(lines, tracks, mergeRunTimes(existing, learned))
+	_, err = data.ParseRunTimes(encoded, lines)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+	err = os.WriteFile(*out, encoded, 0644)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// currentLineData is the line data the run times are written with, from the lines file if
+// there is one, otherwise the built in line data
+func currentLineData(path string) ([]data.LineNameDataPair, []data.Track, []data.RunTime, error) {
+	if path == "" {
+		return data.DefaultLines(), data.DefaultTracks(), data.DefaultRunTimes(), nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	lines, err := data.ParseLines(b)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	tracks, err := data.ParseTracks(b, lines)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	runTimes, err := data.ParseRunTimes(b, lines)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return lines, tracks, runTimes, nil
+}
+
+// mergeRunTimes replaces existing run times with learned ones for the same segment, and adds
+// the rest of the learned ones after them
+func mergeRunTimes(existing, learned []data.RunTime) []data.RunTime {
+	byKey := make(map[string]data.RunTime)
+	for _, r := range learned {
+		byKey[r.From+"-"+r.To] = r
+	}
+
+	out := make([]data.RunTime, 0, len(existing)+len(learned))
+	for _, r := range existing {
+		key := r.From + "-" + r.To
+		if l, ok := byKey[key]; ok {
+			r = l
+			delete(byKey, key)
+		}
+		out = append(out, r)
+	}
+	for _, r := range learned {
+		if _, ok := byKey[r.From+"-"+r.To]; ok {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func learnArchived(ctx context.Context, dir string, lines []data.LineNameDataPair,
+	learner *recorder.RunTimeLearner, from, to time.Time) (int, error) {
+
+	archive, err := recorder.NewArchive(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = archive.Range(from, to, func(t time.Time, raw map[string]smrt.Result) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for _, l := range lines {
+			learner.Add(t, l.Name, smrt.ToModel(raw, l.Line))
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// learnRecorded pages through the arrivals column a line at a time, like exportRecordedRows
+func learnRecorded(ctx context.Context, store recorder.Store, lines []data.LineNameDataPair,
+	learner *recorder.RunTimeLearner, from, to time.Time) (int, error) {
+
+	count := 0
+	for _, l := range lines {
+		for next := from; next.Before(to); {
+			rows, err := store.QueryRange(ctx, l.Name, next, to, exportPage)
+			if err != nil {
+				return count, err
+			}
+
+			for _, row := range rows {
+				if row.Arrivals == "" {
+					continue
+				}
+				arrivals, err := recorder.DecodeArrivals(row.Arrivals)
+				if err != nil {
+					return count, fmt.Errorf("%s at %s: %w", l.Name, row.Time.Format(time.RFC3339), err)
+				}
+				learner.Add(row.Time, l.Name, arrivals)
+				count++
+			}
+
+			if len(rows) < exportPage {
+				break
+			}
+			// times are stored to the millisecond at best
+			next = rows[len(rows)-1].Time.Add(time.Millisecond)
+		}
+	}
+	return count, nil
+}
@@ -3,16 +3,61 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"time"
 
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/lta"
+	"go.lepak.sg/mrtracker-backend/recorder"
+	"go.lepak.sg/mrtracker-backend/sbs"
 	"go.lepak.sg/mrtracker-backend/server"
+	"go.lepak.sg/mrtracker-backend/server/handler/param"
+	"go.lepak.sg/mrtracker-backend/server/handler/position"
+	"go.lepak.sg/mrtracker-backend/server/notify"
+	"go.lepak.sg/mrtracker-backend/server/publisher"
 )
 
 const (
 	envHost = "HOST"
 	envPort = "PORT"
 
+	envMQTTBroker   = "MQTT_BROKER"
+	envMQTTClientID = "MQTT_CLIENT_ID"
+	envMQTTUsername = "MQTT_USERNAME"
+	envMQTTPassword = "MQTT_PASSWORD"
+	envMQTTPrefix   = "MQTT_TOPIC_PREFIX"
+	envMQTTQoS      = "MQTT_QOS"
+
+	envWebhookURLs   = "WEBHOOK_URLS" // comma separated
+	envWebhookSecret = "WEBHOOK_SECRET"
+
+	// same store as cmd/recorder
+	envStore = "STORE"
+	envDsn   = "DSN"
+
+	envPositionSource = "POSITION_SOURCE" // live (default) or typical
+
+	envLinesFile = "LINES_FILE" // replaces the built in line data
+
+	// NEL, DTL, CCL and Sengkang and Punggol LRT arrivals come from this JSON source if set, see
+	// package sbs
+	envSBSURL = "SBS_URL"
+	envSBSKey = "SBS_KEY"
+
+	// official service alerts are included if the account key is set
+	envLTAAccountKey = "LTA_ACCOUNT_KEY"
+	envLTABaseURL    = "LTA_BASE_URL" // default DataMall
+
+	// replay recordings instead of serving live data if REPLAY_FROM is set
+	envReplayFrom  = "REPLAY_FROM" // unix ms or RFC 3339
+	envReplayTo    = "REPLAY_TO"   // default a day after REPLAY_FROM
+	envReplaySpeed = "REPLAY_SPEED"
+	envReplayLoop  = "REPLAY_LOOP" // true to start again at the end
+
 	defaultHost     = "0.0.0.0"
 	defaultPort     = "8080"
 	defaultPrivAddr = "0.0.0.0:9100" // TODO: restrict to prometheus bridge network only?
@@ -22,6 +67,13 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
+	if path := os.Getenv(envLinesFile); path != "" {
+		err := data.Load(path)
+		if err != nil {
+			log.Fatalf("loading lines: %v", err)
+		}
+	}
+
 	host, ok := os.LookupEnv(envHost)
 	if !ok {
 		host = defaultHost
@@ -32,6 +84,108 @@ func main() {
 		port = defaultPort
 	}
 
-	addr := fmt.Sprintf("%s:%s", host, port)
-	server.StartHttp(ctx, addr, defaultPrivAddr)
+	c := server.Config{
+		Addr:     fmt.Sprintf("%s:%s", host, port),
+		PrivAddr: defaultPrivAddr,
+		DSN:      os.Getenv(envDsn),
+	}
+
+	c.StoreBackend, ok = os.LookupEnv(envStore)
+	if !ok {
+		c.StoreBackend = recorder.BackendMySQL
+	}
+
+	switch source := os.Getenv(envPositionSource); source {
+	case "", "live":
+	case "typical":
+		if c.DSN == "" {
+			log.Fatalf("%s=typical needs %s", envPositionSource, envDsn)
+		}
+		c.Typical = true
+	default:
+		log.Fatalf("invalid %s: %q", envPositionSource, source)
+	}
+
+	if from := os.Getenv(envReplayFrom); from != "" {
+		c.Replay = mustReplayConfig(from)
+		if c.DSN == "" {
+			log.Fatalf("%s needs %s", envReplayFrom, envDsn)
+		}
+	}
+
+	if url := os.Getenv(envSBSURL); url != "" {
+		c.SBS = &sbs.Provider{
+			URL:      url,
+			Key:      os.Getenv(envSBSKey),
+			MaxTries: 3,
+		}
+	}
+
+	if key := os.Getenv(envLTAAccountKey); key != "" {
+		c.LTA = &lta.Client{
+			BaseURL:    os.Getenv(envLTABaseURL),
+			AccountKey: key,
+		}
+	}
+
+	// mqtt is optional
+	if broker := os.Getenv(envMQTTBroker); broker != "" {
+		c.MQTT = &publisher.MQTTConfig{
+			Broker:      broker,
+			ClientID:    os.Getenv(envMQTTClientID),
+			Username:    os.Getenv(envMQTTUsername),
+			Password:    os.Getenv(envMQTTPassword),
+			TopicPrefix: os.Getenv(envMQTTPrefix),
+		}
+		if qos := os.Getenv(envMQTTQoS); qos != "" {
+			n, err := strconv.ParseUint(qos, 10, 8)
+			if err != nil {
+				log.Fatalf("invalid %s: %v", envMQTTQoS, err)
+			}
+			c.MQTT.QoS = byte(n)
+		}
+	}
+
+	if urls := os.Getenv(envWebhookURLs); urls != "" {
+		c.Notify = &notify.Config{
+			URLs:   strings.Split(urls, ","),
+			Secret: os.Getenv(envWebhookSecret),
+		}
+	}
+
+	server.StartHttp(ctx, c)
+}
+
+func mustReplayConfig(from string) *position.ReplayConfig {
+	var err error
+	rc := &position.ReplayConfig{}
+
+	rc.From, err = param.ParseTime(from)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", envReplayFrom, err)
+	}
+
+	rc.To = rc.From.Add(24 * time.Hour)
+	if to := os.Getenv(envReplayTo); to != "" {
+		rc.To, err = param.ParseTime(to)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", envReplayTo, err)
+		}
+	}
+
+	if speed := os.Getenv(envReplaySpeed); speed != "" {
+		rc.Speed, err = strconv.ParseFloat(speed, 64)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", envReplaySpeed, err)
+		}
+	}
+
+	if loop := os.Getenv(envReplayLoop); loop != "" {
+		rc.Loop, err = strconv.ParseBool(loop)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", envReplayLoop, err)
+		}
+	}
+
+	return rc
 }
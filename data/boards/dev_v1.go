@@ -1,5 +1,11 @@
 package boards
 
+import (
+	"fmt"
+
+	"go.lepak.sg/mrtracker-backend/data"
+)
+
 type TM1638OutSpec struct {
 	Chip uint8 // starts from 0
 	Grid uint8 // starts from 1
@@ -264,3 +270,32 @@ var DevV1 = map[string][]TM1638OutSpec{
 		{2, 7, 4}, // TNM_A
 	},
 }
+
+// DevV1Segments is DevV1 keyed by segment label (see data.Line.SegmentLabels) instead of position.
+// The wiring was done against the default lines, so if the loaded lines are different, only the
+// segments that changed are left without an LED.
+var DevV1Segments = segmentsOf(DevV1, data.DefaultLines())
+
+func segmentsOf(specs map[string][]TM1638OutSpec, lines []data.LineNameDataPair) map[string]map[string]TM1638OutSpec {
+	out := make(map[string]map[string]TM1638OutSpec)
+	for _, l := range lines {
+		lineSpecs, ok := specs[l.Name]
+		if !ok {
+			continue
+		}
+
+		labels := l.Line.SegmentLabels()
+		if len(lineSpecs) > len(labels) {
+			panic(fmt.Sprintf("boards: %s has %d LEDs for %d segments", l.Name, len(lineSpecs), len(labels)))
+		}
+
+		out[l.Name] = make(map[string]TM1638OutSpec)
+		for i, spec := range lineSpecs {
+			if _, ok := out[l.Name][labels[i]]; ok {
+				panic(fmt.Sprintf("boards: %s has segment %s twice", l.Name, labels[i]))
+			}
+			out[l.Name][labels[i]] = spec
+		}
+	}
+	return out
+}
@@ -1,3 +1,11 @@
 package data
 
-//go:generate go run gen/main.go
+// lines.json is generated from gen/stations.json and gen/platforms.json, a platform fixture
+// captured from the live API with "go run ./gen -capture". Capture it again when the platforms
+// change, and commit it with the regenerated lines.json; -n shows the diff without writing.
+// Station locations come from gen/stations.json and are approximate, good enough to draw on a
+// map. The LRT platforms in the fixture are written by hand from the service pattern, and as loop
+// platforms all show the hub, gen/hints.json names them. Tracks are drawn by hand and kept when
+// the file is regenerated.
+//
+//go:generate go run ./gen -stations gen/stations.json -platforms gen/platforms.json -hints gen/hints.json -lines ns,ew,cg,ne,bp,sk,pg -o lines.json
@@ -3,11 +3,11 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -18,6 +18,19 @@ import (
 /*
 Generate line data
 
+Inputs are local files, so the output only changes when they do:
+
+  - the station list, in the format of wikipedia-mrt.json from github.com/cheeaun/sgraildata
+  - a platform fixture, the smrt results for every station keyed by station name (the format of
+    last.json and the recorder archive). -capture writes one from the live API.
+
+Each line is the stations with its code prefix in code order, and the reverse. A loop, like the
+LRT loops, starts and ends at its hub station. The platform for a station is the one whose trains
+head furthest along the line, or at the last station, the one whose trains head back, as that is
+the platform a terminating train arrives at. A station without exactly one such platform is an
+error, nothing is written and the exit status is 1. Loop platforms usually all show the hub, so
+-hints can name the platform for a station on a line, eg {"bpl1": {"BP7": "PTR_A"}}.
+
 Source data: github.com/cheeaun/sgraildata licensed under the ISC License (presumed in package.json)
 Notice of source data follows:
 
@@ -33,112 +46,463 @@ ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CO
 THIS SOFTWARE.
 */
 
-const (
-	path = "https://raw.githubusercontent.com/cheeaun/sgraildata/master/data/raw/wikipedia-mrt.json"
-)
+// lineSpec is a service to generate, in both directions
+type lineSpec struct {
+	// line names of the two directions
+	names [2]string
+	// stations with a code with this prefix, numbered between from and to (0 for no limit)
+	prefix   string
+	from, to int
+	// code of the station a loop starts and ends at, if it has no code with the prefix
+	hub  string
+	loop bool
+}
+
+// groups of lines -lines can ask for, in output order
+var groups = []struct {
+	name  string
+	specs []lineSpec
+}{
+	{"ns", []lineSpec{{names: [2]string{"ns1", "ns2"}, prefix: "NS"}}},
+	{"ew", []lineSpec{{names: [2]string{"ew1", "ew2"}, prefix: "EW"}}},
+	{"cg", []lineSpec{{names: [2]string{"cg1", "cg2"}, prefix: "CG"}}},
+	{"ne", []lineSpec{{names: [2]string{"ne1", "ne2"}, prefix: "NE"}}},
+	{"bp", []lineSpec{
+		{names: [2]string{"bp1", "bp2"}, prefix: "BP", to: 6},
+		{names: [2]string{"bpl1", "bpl2"}, prefix: "BP", from: 6, loop: true},
+	}},
+	{"sk", []lineSpec{
+		{names: [2]string{"se1", "se2"}, prefix: "SE", hub: "STC", loop: true},
+		{names: [2]string{"sw1", "sw2"}, prefix: "SW", hub: "STC", loop: true},
+	}},
+	{"pg", []lineSpec{
+		{names: [2]string{"pe1", "pe2"}, prefix: "PE", hub: "PTC", loop: true},
+		{names: [2]string{"pw1", "pw2"}, prefix: "PW", hub: "PTC", loop: true},
+	}},
+}
+
+var numberedCodePattern = regexp.MustCompile(`^[A-Z]{2}[0-9]+$`)
+
+type rawStation struct {
+	Codes []string `json:"codes"`
+	Name  string   `json:"name"`
+	// Approximate location, carried through to lines.json
+	Lat float64 `json:"lat,omitempty"`
+	Lng float64 `json:"lng,omitempty"`
+}
 
 func main() {
-	var rawData []struct {
-		Codes []string
-		Name  string
+	stationsPath := flag.String("stations", "gen/stations.json", "station list")
+	platformsPath := flag.String("platforms", "gen/platforms.json", "platform fixture")
+	out := flag.String("o", "lines.json", "output file, compared against before writing")
+	dryRun := flag.Bool("n", false, "only print the diff")
+	hintsPath := flag.String("hints", "", "platforms to use where they can't be worked out")
+	lineGroups := flag.String("lines", "ns,ew,cg", "groups of lines to generate: ns, ew, cg, ne, bp, sk, pg")
+	capture := flag.Bool("capture", false, "query the live API and write the platform fixture instead")
+	flag.Parse()
+
+	specs, err := selectSpecs(*lineGroups)
+	if err == nil {
+		err = run(specs, *stationsPath, *platformsPath, *hintsPath, *out, *dryRun, *capture)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// selectSpecs returns the specs of the comma separated groups, in output order
+func selectSpecs(names string) ([]lineSpec, error) {
+	want := make(map[string]bool)
+	for _, name := range strings.Split(names, ",") {
+		want[strings.TrimSpace(name)] = true
+	}
+
+	var out []lineSpec
+	for _, g := range groups {
+		if want[g.name] {
+			out = append(out, g.specs...)
+			delete(want, g.name)
+		}
+	}
+	for name := range want {
+		return nil, fmt.Errorf("unknown line group %q", name)
+	}
+	return out, nil
+}
+
+func run(specs []lineSpec, stationsPath, platformsPath, hintsPath, out string, dryRun, capture bool) error {
+	raw, err := readStations(stationsPath)
+	if err != nil {
+		return err
+	}
+
+	if capture {
+		return capturePlatforms(specs, raw, platformsPath)
+	}
+
+	// line name, station code, platform ID
+	hints := make(map[string]map[string]string)
+	if hintsPath != "" {
+		b, err := os.ReadFile(hintsPath)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(b, &hints)
+		if err != nil {
+			return fmt.Errorf("%s: %w", hintsPath, err)
+		}
 	}
 
-	resp, err := http.Get(path)
+	b, err := os.ReadFile(platformsPath)
+	if err != nil {
+		return err
+	}
+	var platforms map[string]smrt.Result
+	err = json.Unmarshal(b, &platforms)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("%s: %w", platformsPath, err)
 	}
 
-	buf, err := io.ReadAll(resp.Body)
+	lines, err := generate(specs, raw, platforms, hints)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	current, err := os.ReadFile(out)
+	if errors.Is(err, os.ErrNotExist) {
+		current = data.EncodeLineData(data.DefaultLines(), data.DefaultTracks(), data.DefaultRunTimes())
+	} else if err != nil {
+		return err
 	}
 
-	err = resp.Body.Close()
+	// tracks are drawn by hand and run times entered or learned separately, so carry them over
+	tracks, err := data.ParseTracks(current, lines)
+	if err != nil {
+		return fmt.Errorf("%s: %w", out, err)
+	}
+	runTimes, err := data.ParseRunTimes(current, lines)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("%s: %w", out, err)
 	}
 
-	err = json.Unmarshal(buf, &rawData)
+	encoded := data.EncodeLineData(lines, tracks, runTimes)
+	_, err = data.ParseLines(encoded)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	changes := diff(splitLines(current), splitLines(encoded))
+	if len(changes) == 0 {
+		fmt.Println("no changes")
+	} else {
+		fmt.Printf("--- %s\n+++ generated\n", out)
+		for _, c := range changes {
+			fmt.Println(c)
+		}
 	}
 
-	lines := map[string]data.Line{
-		"NS": {},
-		"EW": {},
-		"CG": {},
+	if dryRun {
+		return nil
 	}
+	return os.WriteFile(out, encoded, 0644)
+}
 
-	for _, el := range rawData {
-		for _, code := range el.Codes {
-			if _, ok := lines[code[:2]]; ok {
-				lines[code[:2]] = append(lines[code[:2]], data.Station{Code: code, Name: el.Name})
+func readStations(path string) ([]rawStation, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw []rawStation
+	err = json.Unmarshal(b, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return raw, nil
+}
+
+// generate builds every line from the station list and picks each station's platform from the
+// fixture, or hints. All problems are collected so one run shows everything that needs fixing.
+func generate(specs []lineSpec, raw []rawStation, platforms map[string]smrt.Result,
+	hints map[string]map[string]string) ([]data.LineNameDataPair, error) {
+
+	var problems []string
+	var out []data.LineNameDataPair
+
+	for _, spec := range specs {
+		forward, err := lineStations(raw, spec)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		reverse := make(data.Line, len(forward))
+		for i := range forward {
+			reverse[len(forward)-1-i] = forward[i]
+		}
+
+		for dir, l := range []data.Line{forward, reverse} {
+			name := spec.names[dir]
+			for i := range l {
+				if spec.loop && i == len(l)-1 {
+					// back at the hub, on the platform the loop started from
+					l[i] = l[0]
+					continue
+				}
+
+				id, err := hintedPlatform(hints[name][l[i].Code], platforms[l[i].Name])
+				if id == "" && err == nil {
+					id, err = choosePlatform(l, i, platforms[l[i].Name])
+				}
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("%s %s %s: %v", name, l[i].Code, l[i].Name, err))
+					continue
+				}
+				parts := strings.SplitN(id, "_", 2)
+				l[i].Code3, l[i].Platform = parts[0], parts[1]
 			}
+			out = append(out, data.LineNameDataPair{Name: name, Line: l})
 		}
 	}
 
-	var linenames []string
-	for k := range lines {
-		linenames = append(linenames, k)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%d problems:\n\t%s", len(problems), strings.Join(problems, "\n\t"))
 	}
+	return out, nil
+}
 
-	for _, k := range linenames {
-		sort.Sort(lines[k])
+// lineStations returns the stations with a code for the line, in code order. A code without a
+// number, like CG at Tanah Merah, is the start of a branch and is shown with the station's own
+// code, and so is a loop's hub. A loop ends with its first station again.
+func lineStations(raw []rawStation, spec lineSpec) (data.Line, error) {
+	prefix := spec.prefix
+	var l data.Line
+	for _, s := range raw {
+		for _, code := range s.Codes {
+			if !strings.HasPrefix(code, prefix) {
+				continue
+			}
+			st := data.Station{Code: code, Name: s.Name, Lat: s.Lat, Lng: s.Lng}
+			if n := st.CodeNum(); (spec.from > 0 && n < spec.from) || (spec.to > 0 && n > spec.to) {
+				continue
+			}
+			l = append(l, st)
+		}
+	}
 
-		lines[k+"_1"] = lines[k]
+	sort.SliceStable(l, func(i, j int) bool {
+		if l[i].CodeNum() != l[j].CodeNum() {
+			return l[i].CodeNum() < l[j].CodeNum()
+		}
+		return l[i].Code < l[j].Code
+	})
 
-		reverse := make(data.Line, len(lines[k]))
-		copy(reverse, lines[k])
-		sort.Sort(sort.Reverse(reverse))
+	if spec.hub != "" {
+		hub, ok := stationWithCode(raw, spec.hub)
+		if !ok {
+			return nil, fmt.Errorf("%s: no station has hub code %s", prefix, spec.hub)
+		}
+		l = append(data.Line{{Code: spec.hub, Name: hub.Name, Lat: hub.Lat, Lng: hub.Lng}}, l...)
+	}
+	if len(l) < 2 {
+		return nil, fmt.Errorf("%s: fewer than 2 stations", prefix)
+	}
 
-		lines[k+"_2"] = reverse
+	for i := range l {
+		if numberedCodePattern.MatchString(l[i].Code) {
+			continue
+		}
+		own := numberedCode(raw, l[i].Name)
+		if own == "" {
+			return nil, fmt.Errorf("%s: %s has no numbered code", prefix, l[i].Name)
+		}
+		l[i].Code = own
+	}
 
-		delete(lines, k)
+	if spec.loop {
+		l = append(l, l[0])
 	}
+	return l, nil
+}
 
-	for k, line := range lines {
-		end := line[len(line)-1].Name
-	outerLoop:
-		for i, station := range line {
-			res, _, err := smrt.GetOne(context.Background(), 5, station.Name)
-			if err != nil {
-				log.Printf("error [%s][%s]: %v", k, station.Name, err)
+func stationWithCode(raw []rawStation, code string) (rawStation, bool) {
+	for _, s := range raw {
+		for _, c := range s.Codes {
+			if c == code {
+				return s, true
+			}
+		}
+	}
+	return rawStation{}, false
+}
+
+func numberedCode(raw []rawStation, name string) string {
+	for _, s := range raw {
+		if s.Name != name {
+			continue
+		}
+		for _, code := range s.Codes {
+			if numberedCodePattern.MatchString(code) {
+				return code
+			}
+		}
+	}
+	return ""
+}
+
+// hintedPlatform checks a platform given by -hints is one the station has, "" if there is no hint
+func hintedPlatform(id string, results smrt.Result) (string, error) {
+	if id == "" {
+		return "", nil
+	}
+	for _, r := range results {
+		if r.PlatformID == id {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("hinted platform %s isn't in the platform fixture", id)
+}
+
+// choosePlatform picks the platform for station i of l from its results
+func choosePlatform(l data.Line, i int, results smrt.Result) (string, error) {
+	if len(results) == 0 {
+		return "", errors.New("not in the platform fixture")
+	}
+
+	// a loop's hub is at both ends, and counts as the end
+	index := make(map[string]int)
+	for j, s := range l {
+		index[s.Name] = j
+	}
+	last := i == len(l)-1
+
+	// how far along the line (or back, at the last station) the platform's trains go. The next
+	// train decides; the subsequent one is only looked at when no next train does, as a platform
+	// shared with short runs can have the next train going the other way.
+	var scores map[string]int
+	for _, dest := range []func(smrt.NextTrains) string{
+		func(r smrt.NextTrains) string { return r.NextTrainDestination },
+		func(r smrt.NextTrains) string { return r.SubseqTrainDestination },
+	} {
+		scores = make(map[string]int)
+		for _, r := range results {
+			if !r.Valid() || len(strings.SplitN(r.PlatformID, "_", 2)) != 2 {
 				continue
 			}
-			for _, resPlatform := range res {
-				if resPlatform.NextTrainDestination == end {
-					// copy this platform data
-					substrings := strings.Split(resPlatform.PlatformID, "_")
-					line[i].Code3 = substrings[0]
-					line[i].Platform = substrings[1]
-					continue outerLoop
-				}
+			j, ok := index[dest(r)]
+			if !ok {
+				continue
+			}
+			score := j - i
+			if last {
+				score = i - j
+			}
+			if score > 0 {
+				scores[r.PlatformID] = score
+			}
+		}
+		if len(scores) > 0 {
+			break
+		}
+	}
+
+	if len(scores) == 0 {
+		return "", errors.New("no hit")
+	}
+
+	var best []string
+	bestScore := 0
+	for id, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore = []string{id}, score
+		case score == bestScore:
+			best = append(best, id)
+		}
+	}
+	if len(best) > 1 {
+		sort.Strings(best)
+		return "", fmt.Errorf("ambiguous, could be any of %s", strings.Join(best, ", "))
+	}
+	return best[0], nil
+}
+
+// capturePlatforms queries the live API for every station on the lines and writes the fixture
+func capturePlatforms(specs []lineSpec, raw []rawStation, path string) error {
+	var names []string
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		l, err := lineStations(raw, spec)
+		if err != nil {
+			return err
+		}
+		for _, s := range l {
+			if !seen[s.Name] {
+				seen[s.Name] = true
+				names = append(names, s.Name)
 			}
-			log.Printf("no hit for [%s][%s]", k, station.Name)
 		}
 	}
 
-	f, err := os.Create("lines.go")
+	results, _, err := smrt.GetN(context.Background(), 4, 5, names...)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	var missing []string
+	for _, name := range names {
+		if len(results[name]) == 0 {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no results for %s", strings.Join(missing, ", "))
 	}
-	defer f.Close()
 
-	_, err = f.WriteString("package data\n\n// Generated by data/gen/main.go, do not edit\n\n")
+	b, err := json.MarshalIndent(results, "", "  ")
 	if err != nil {
-		panic(err)
+		return err
 	}
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
+
+func splitLines(b []byte) []string {
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
 
-	linenames = nil
-	for k := range lines {
-		linenames = append(linenames, k)
+// diff returns the lines removed from a and added in b, in order, prefixed with - and +
+func diff(a, b []string) []string {
+	// longest common subsequence, the files are a few hundred lines
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
 	}
-	sort.Strings(linenames)
 
-	for _, k := range linenames {
-		_, err = f.WriteString(fmt.Sprintf("var %s = %s\n", k, lines[k].Repr()))
-		if err != nil {
-			panic(err)
+	var out []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "-"+a[i])
+			i++
+		default:
+			out = append(out, "+"+b[j])
+			j++
 		}
 	}
+	return out
 }
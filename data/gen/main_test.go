@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+func TestChoosePlatform(t *testing.T) {
+	ew := data.Line{
+		{Name: "Pasir Ris"}, {Name: "Tanah Merah"}, {Name: "Jurong East"}, {Name: "Joo Koon"}, {Name: "Tuas Link"},
+	}
+	platform := func(id, next, subseq string) smrt.NextTrains {
+		return smrt.NextTrains{PlatformID: id, NextTrainDestination: next, SubseqTrainDestination: subseq}
+	}
+
+	cases := []struct {
+		name    string
+		i       int
+		results smrt.Result
+		want    string
+	}{
+		{"to the end", 1, smrt.Result{
+			platform("TNM_A", "Pasir Ris", "Pasir Ris"),
+			platform("TNM_B", "Tuas Link", "Joo Koon"),
+			platform("TNM_C", "Changi Airport", "Changi Airport"),
+		}, "TNM_B"},
+		{"short runs", 2, smrt.Result{
+			platform("JUR_B", "Pasir Ris", "Pasir Ris"),
+			platform("JUR_F", "Joo Koon", "Joo Koon"),
+		}, "JUR_F"},
+		{"next train decides", 3, smrt.Result{
+			platform("JKN_A", "Pasir Ris", "Tuas Link"),
+			platform("JKN_B", "Tuas Link", "Do not board"),
+		}, "JKN_B"},
+		{"terminal", 4, smrt.Result{
+			platform("TLK_A", "Pasir Ris", "Pasir Ris"),
+		}, "TLK_A"},
+		{"no hit", 1, smrt.Result{
+			platform("TNM_A", "Pasir Ris", "Pasir Ris"),
+		}, ""},
+		{"ambiguous", 1, smrt.Result{
+			platform("TNM_B", "Tuas Link", "Tuas Link"),
+			platform("TNM_D", "Tuas Link", "Tuas Link"),
+		}, ""},
+		{"missing", 1, nil, ""},
+	}
+
+	for _, c := range cases {
+		got, err := choosePlatform(ew, c.i, c.results)
+		if c.want == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", c.name, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s: got %q, %v, want %q", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := []string{"a", "b", "c", "d"}
+	b := []string{"a", "c", "x", "d", "e"}
+	want := []string{"-b", "+x", "+e"}
+	if got := diff(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := diff(a, a); len(got) != 0 {
+		t.Errorf("expected no changes, got %v", got)
+	}
+}
+
+func TestGenerateLoop(t *testing.T) {
+	raw := []rawStation{
+		{Codes: []string{"NE16", "STC"}, Name: "Sengkang"},
+		{Codes: []string{"SE1"}, Name: "Compassvale"},
+		{Codes: []string{"SE2"}, Name: "Rumbia"},
+		{Codes: []string{"SE3"}, Name: "Bakau"},
+	}
+	spec := lineSpec{names: [2]string{"se1", "se2"}, prefix: "SE", hub: "STC", loop: true}
+
+	// every platform shows the hub, so only the hints tell the directions apart
+	platforms := make(map[string]smrt.Result)
+	hints := map[string]map[string]string{"se1": {}, "se2": {}}
+	for _, s := range []struct{ name, code3 string }{{"Sengkang", "STK"}, {"Compassvale", "CPV"}, {"Rumbia", "RMB"}, {"Bakau", "BKU"}} {
+		for _, p := range []string{"A", "B"} {
+			platforms[s.name] = append(platforms[s.name], smrt.NextTrains{
+				PlatformID: s.code3 + "_" + p, NextTrainDestination: "Sengkang", SubseqTrainDestination: "Sengkang",
+			})
+		}
+	}
+
+	_, err := generate([]lineSpec{spec}, raw, platforms, hints)
+	if err == nil {
+		t.Fatal("expected the loop to be ambiguous without hints")
+	}
+
+	for code, code3 := range map[string]string{"NE16": "STK", "SE1": "CPV", "SE2": "RMB", "SE3": "BKU"} {
+		hints["se1"][code] = code3 + "_A"
+		hints["se2"][code] = code3 + "_B"
+	}
+	lines, err := generate([]lineSpec{spec}, raw, platforms, hints)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, s := range lines[0].Line {
+		got = append(got, s.Code+" "+s.PlatformID())
+	}
+	want := []string{"NE16 STK_A", "SE1 CPV_A", "SE2 RMB_A", "SE3 BKU_A", "NE16 STK_A"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("se1: got %v, want %v", got, want)
+	}
+	if !lines[0].Line.Loop() || !lines[1].Line.Loop() || lines[1].Line[1].Code != "SE3" {
+		t.Errorf("se2: got %v", lines[1].Line)
+	}
+
+	_, err = data.ParseLines(data.EncodeLines(lines))
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,169 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// LatLng is a point on the map, in degrees
+type LatLng [2]float64
+
+// Track is the shape of the track between two adjacent stations, for drawing on a map.
+// Tracks are in the "tracks" list of lines.json and go both ways:
+//
+//	"tracks": [
+//	  {"from": "EW1", "to": "EW2", "points": [[1.3702, 103.9479], ...]},
+//
+// Points are between the stations, not including them. Without a track, the stations are
+// joined with a straight line.
+type Track struct {
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Points []LatLng `json:"points"`
+}
+
+// line colours by station code prefix
+var colours = map[string]string{
+	"NS": "#D42E12",
+	"EW": "#009645",
+	"CG": "#009645",
+	"NE": "#9900AA",
+	"CC": "#FA9E0D",
+	"CE": "#FA9E0D",
+	"DT": "#005EC4",
+	"TE": "#9D5B25",
+	"BP": "#748477",
+	"SE": "#748477",
+	"SW": "#748477",
+	"PE": "#748477",
+	"PW": "#748477",
+}
+
+const defaultColour = "#999999"
+
+// Colour returns the official colour of the line, from the code of the station in the middle
+// of it, so a branch like cg1 starting at EW4 gets its own colour
+func (l Line) Colour() string {
+	if len(l) == 0 {
+		return defaultColour
+	}
+	if c, ok := colours[codePrefix(l[len(l)/2].Code)]; ok {
+		return c
+	}
+	return defaultColour
+}
+
+// HasLocation reports whether the station has coordinates
+func (s Station) HasLocation() bool {
+	return s.Lat != 0 || s.Lng != 0
+}
+
+// Location returns the station's coordinates
+func (s Station) Location() LatLng {
+	return LatLng{s.Lat, s.Lng}
+}
+
+// ParseTracks reads and validates the tracks in a file in the lines.json format
+func ParseTracks(b []byte, lines []LineNameDataPair) ([]Track, error) {
+	var f linesFile
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&f)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, l := range lines {
+		for _, s := range l.Line {
+			known[s.Code] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, t := range f.Tracks {
+		if !known[t.From] || !known[t.To] {
+			return nil, fmt.Errorf("track %d: unknown station %s or %s", i, t.From, t.To)
+		}
+		if seen[trackKey(t.From, t.To)] || seen[trackKey(t.To, t.From)] {
+			return nil, fmt.Errorf("track %d: %s-%s appears twice", i, t.From, t.To)
+		}
+		seen[trackKey(t.From, t.To)] = true
+	}
+	return f.Tracks, nil
+}
+
+func trackKey(from, to string) string {
+	return from + "-" + to
+}
+
+// trackSet is tracks by "from-to", both ways round
+type trackSet map[string][]LatLng
+
+func newTrackSet(tracks []Track) trackSet {
+	out := make(trackSet)
+	for _, t := range tracks {
+		out[trackKey(t.From, t.To)] = t.Points
+		reverse := make([]LatLng, len(t.Points))
+		for i, p := range t.Points {
+			reverse[len(t.Points)-1-i] = p
+		}
+		out[trackKey(t.To, t.From)] = reverse
+	}
+	return out
+}
+
+// GetPath returns the shape of the track from one station to the next, both ends included.
+// It is nil if either station has no location.
+func GetPath(from, to Station) []LatLng {
+	if !from.HasLocation() || !to.HasLocation() {
+		return nil
+	}
+
+	linesLock.RLock()
+	points := loadedTracks[trackKey(from.Code, to.Code)]
+	linesLock.RUnlock()
+
+	out := make([]LatLng, 0, len(points)+2)
+	out = append(out, from.Location())
+	out = append(out, points...)
+	return append(out, to.Location())
+}
+
+// Along returns the point the fraction f of the way along the path, by distance
+func Along(path []LatLng, f float64) LatLng {
+	if len(path) == 0 {
+		return LatLng{}
+	}
+	total := 0.0
+	for i := 1; i < len(path); i++ {
+		total += distance(path[i-1], path[i])
+	}
+
+	want := total * math.Max(0, math.Min(1, f))
+	for i := 1; i < len(path); i++ {
+		d := distance(path[i-1], path[i])
+		if d > 0 && want <= d {
+			t := want / d
+			return LatLng{
+				path[i-1][0] + (path[i][0]-path[i-1][0])*t,
+				path[i-1][1] + (path[i][1]-path[i-1][1])*t,
+			}
+		}
+		want -= d
+	}
+	return path[len(path)-1]
+}
+
+// distance is good enough for comparing lengths close to the equator
+func distance(a, b LatLng) float64 {
+	return math.Hypot(a[0]-b[0], a[1]-b[1])
+}
+
+// formatCoord writes a coordinate with as few digits as it needs
+func formatCoord(f float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.6f", f), "0"), ".")
+}
@@ -0,0 +1,52 @@
+package data
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAlong(t *testing.T) {
+	path := []LatLng{{0, 0}, {0, 1}, {2, 1}}
+	cases := []struct {
+		f    float64
+		want LatLng
+	}{
+		{0, LatLng{0, 0}},
+		{0.25, LatLng{0, 0.75}},
+		{0.5, LatLng{0.5, 1}},
+		{1, LatLng{2, 1}},
+		{2, LatLng{2, 1}},
+	}
+	for _, c := range cases {
+		got := Along(path, c.f)
+		if math.Abs(got[0]-c.want[0]) > 1e-9 || math.Abs(got[1]-c.want[1]) > 1e-9 {
+			t.Errorf("%v: got %v, want %v", c.f, got, c.want)
+		}
+	}
+}
+
+func TestTracks(t *testing.T) {
+	lines := DefaultLines()
+	for _, l := range lines {
+		for _, s := range l.Line {
+			if !s.HasLocation() {
+				t.Errorf("%s: %s has no location", l.Name, s.Code)
+			}
+		}
+	}
+
+	b := EncodeLines(lines, Track{From: "EW1", To: "EW2", Points: []LatLng{{1.36, 103.95}}})
+	tracks, err := ParseTracks(b, lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := newTrackSet(tracks)
+	if p := set[trackKey("EW2", "EW1")]; len(p) != 1 || p[0] != (LatLng{1.36, 103.95}) {
+		t.Errorf("reverse track: got %v", p)
+	}
+
+	_, err = ParseTracks(EncodeLines(lines, Track{From: "EW1", To: "XX9"}), lines)
+	if err == nil {
+		t.Error("expected an error for an unknown station")
+	}
+}
@@ -0,0 +1,179 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrNoStation is returned by Index.Lookup when nothing matches
+var ErrNoStation = errors.New("no such station")
+
+// AmbiguousError is returned by Index.Lookup when a name matches more than one station
+type AmbiguousError struct {
+	Query   string
+	Matches []string
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%q could be any of %s", e.Query, strings.Join(e.Matches, ", "))
+}
+
+// Index finds stations by any of the things they are called: a code ("EW4"), the three letter
+// code ("TNM"), a platform ID ("TNM_B") or the name, ignoring case, spaces and punctuation and
+// forgiving a typo or two.
+type Index struct {
+	// upper case codes, three letter codes and platform IDs
+	byKey map[string]*Node
+	// normalised names
+	byName    map[string]*Node
+	names     []string
+	platforms map[string][]*Stop
+}
+
+// maximum edits for a name to still match
+const maxNameDistance = 2
+
+// NewIndex indexes every station of the network
+func NewIndex(n *Network) *Index {
+	x := &Index{
+		byKey:     make(map[string]*Node),
+		byName:    make(map[string]*Node),
+		platforms: make(map[string][]*Stop),
+	}
+
+	for _, node := range n.Stations() {
+		x.byKey[node.Code3] = node
+		for _, c := range node.Codes {
+			x.byKey[c] = node
+		}
+		for _, s := range node.Stops {
+			id := s.AsStation().PlatformID()
+			x.byKey[id] = node
+			x.platforms[id] = append(x.platforms[id], s)
+		}
+
+		name := normaliseName(node.Name)
+		x.byName[name] = node
+		x.names = append(x.names, name)
+	}
+	sort.Strings(x.names)
+
+	return x
+}
+
+// Lookup returns the station q refers to. Codes and platform IDs must match exactly, ignoring
+// case. Names are tried exactly, then as the start of one name, then within one name, then by
+// edit distance, and the first of those to find exactly one station wins.
+func (x *Index) Lookup(q string) (*Node, error) {
+	if node, ok := x.byKey[strings.ToUpper(strings.TrimSpace(q))]; ok {
+		return node, nil
+	}
+
+	name := normaliseName(q)
+	if name == "" {
+		return nil, ErrNoStation
+	}
+	if node, ok := x.byName[name]; ok {
+		return node, nil
+	}
+
+	var matches []string
+	for _, match := range []func(string) bool{
+		func(n string) bool { return strings.HasPrefix(n, name) },
+		func(n string) bool { return strings.Contains(n, name) },
+	} {
+		matches = matches[:0]
+		for _, n := range x.names {
+			if match(n) {
+				matches = append(matches, n)
+			}
+		}
+		if len(matches) > 0 {
+			return x.one(q, matches)
+		}
+	}
+
+	best := maxNameDistance + 1
+	for _, n := range x.names {
+		d := editDistance(n, name)
+		switch {
+		case d < best:
+			best, matches = d, []string{n}
+		case d == best:
+			matches = append(matches, n)
+		}
+	}
+	if best > maxNameDistance {
+		return nil, ErrNoStation
+	}
+	return x.one(q, matches)
+}
+
+func (x *Index) one(q string, matches []string) (*Node, error) {
+	if len(matches) == 1 {
+		return x.byName[matches[0]], nil
+	}
+	e := &AmbiguousError{Query: q}
+	for _, m := range matches {
+		e.Matches = append(e.Matches, x.byName[m].Name)
+	}
+	return nil, e
+}
+
+// Platform returns the stops of every line using the platform, eg "TNM_C" for cg1 and cg2
+func (x *Index) Platform(id string) []*Stop {
+	return x.platforms[strings.ToUpper(strings.TrimSpace(id))]
+}
+
+// normaliseName lower cases a name and drops everything but letters and digits
+func normaliseName(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(br)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// GetIndex returns the index of the loaded lines. The result is shared, don't modify it.
+func GetIndex() *Index {
+	linesLock.RLock()
+	defer linesLock.RUnlock()
+	return loadedIndex
+}
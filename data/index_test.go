@@ -0,0 +1,52 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIndexLookup(t *testing.T) {
+	n, err := NewNetwork(DefaultLines())
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := NewIndex(n)
+
+	found := map[string]string{
+		"EW4":           "Tanah Merah",
+		"ew4":           "Tanah Merah",
+		"TNM":           "Tanah Merah",
+		"tnm_b":         "Tanah Merah",
+		"NS1":           "Jurong East",
+		"EW24":          "Jurong East",
+		"jurong east":   "Jurong East",
+		"raffles-place": "Raffles Place",
+		"bukit b":       "Bukit Batok",
+		"Tampinse":      "Tampines",
+		"payoh":         "Toa Payoh",
+	}
+	for q, want := range found {
+		node, err := x.Lookup(q)
+		if err != nil {
+			t.Errorf("%s: %v", q, err)
+			continue
+		}
+		if node.Name != want {
+			t.Errorf("%s: got %s, want %s", q, node.Name, want)
+		}
+	}
+
+	var ambiguous *AmbiguousError
+	if _, err = x.Lookup("bukit"); !errors.As(err, &ambiguous) {
+		t.Errorf("bukit: expected ambiguous, got %v", err)
+	}
+	for _, q := range []string{"", "XYZ", "nowhere at all"} {
+		if _, err = x.Lookup(q); err != ErrNoStation {
+			t.Errorf("%q: expected no station, got %v", q, err)
+		}
+	}
+
+	if stops := x.Platform("TNM_C"); len(stops) != 2 {
+		t.Errorf("TNM_C: got %d stops, want cg1 and cg2", len(stops))
+	}
+}
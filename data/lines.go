@@ -1,775 +0,0 @@
-package data
-
-var CG_1 = Line{
-	Station{
-		Code:     "EW4",
-		Code3:    "TNM",
-		Platform: "C",
-		Name:     "Tanah Merah",
-	},
-	Station{
-		Code:     "CG1",
-		Code3:    "XPO",
-		Platform: "A",
-		Name:     "Expo",
-	},
-	Station{
-		Code:     "CG2",
-		Code3:    "CGA",
-		Platform: "A",
-		Name:     "Changi Airport",
-	},
-}
-
-var CG_2 = Line{
-	Station{
-		Code:     "CG2",
-		Code3:    "CGA",
-		Platform: "A",
-		Name:     "Changi Airport",
-	},
-	Station{
-		Code:     "CG1",
-		Code3:    "XPO",
-		Platform: "B",
-		Name:     "Expo",
-	},
-	Station{
-		Code:     "EW4",
-		Code3:    "TNM",
-		Platform: "C",
-		Name:     "Tanah Merah",
-	},
-}
-
-var EW_1 = Line{
-	Station{
-		Code:     "EW1",
-		Code3:    "PSR",
-		Platform: "A",
-		Name:     "Pasir Ris",
-	},
-	Station{
-		Code:     "EW2",
-		Code3:    "TAM",
-		Platform: "B",
-		Name:     "Tampines",
-	},
-	Station{
-		Code:     "EW3",
-		Code3:    "SIM",
-		Platform: "B",
-		Name:     "Simei",
-	},
-	Station{
-		Code:     "EW4",
-		Code3:    "TNM",
-		Platform: "B",
-		Name:     "Tanah Merah",
-	},
-	Station{
-		Code:     "EW5",
-		Code3:    "BDK",
-		Platform: "B",
-		Name:     "Bedok",
-	},
-	Station{
-		Code:     "EW6",
-		Code3:    "KEM",
-		Platform: "B",
-		Name:     "Kembangan",
-	},
-	Station{
-		Code:     "EW7",
-		Code3:    "EUN",
-		Platform: "B",
-		Name:     "Eunos",
-	},
-	Station{
-		Code:     "EW8",
-		Code3:    "PYL",
-		Platform: "B",
-		Name:     "Paya Lebar",
-	},
-	Station{
-		Code:     "EW9",
-		Code3:    "ALJ",
-		Platform: "B",
-		Name:     "Aljunied",
-	},
-	Station{
-		Code:     "EW10",
-		Code3:    "KAL",
-		Platform: "B",
-		Name:     "Kallang",
-	},
-	Station{
-		Code:     "EW11",
-		Code3:    "LVR",
-		Platform: "B",
-		Name:     "Lavender",
-	},
-	Station{
-		Code:     "EW12",
-		Code3:    "BGS",
-		Platform: "B",
-		Name:     "Bugis",
-	},
-	Station{
-		Code:     "EW13",
-		Code3:    "CTH",
-		Platform: "B",
-		Name:     "City Hall",
-	},
-	Station{
-		Code:     "EW14",
-		Code3:    "RFP",
-		Platform: "C",
-		Name:     "Raffles Place",
-	},
-	Station{
-		Code:     "EW15",
-		Code3:    "TPG",
-		Platform: "B",
-		Name:     "Tanjong Pagar",
-	},
-	Station{
-		Code:     "EW16",
-		Code3:    "OTP",
-		Platform: "B",
-		Name:     "Outram Park",
-	},
-	Station{
-		Code:     "EW17",
-		Code3:    "TIB",
-		Platform: "B",
-		Name:     "Tiong Bahru",
-	},
-	Station{
-		Code:     "EW18",
-		Code3:    "RDH",
-		Platform: "B",
-		Name:     "Redhill",
-	},
-	Station{
-		Code:     "EW19",
-		Code3:    "QUE",
-		Platform: "B",
-		Name:     "Queenstown",
-	},
-	Station{
-		Code:     "EW20",
-		Code3:    "COM",
-		Platform: "B",
-		Name:     "Commonwealth",
-	},
-	Station{
-		Code:     "EW21",
-		Code3:    "BNV",
-		Platform: "B",
-		Name:     "Buona Vista",
-	},
-	Station{
-		Code:     "EW22",
-		Code3:    "DVR",
-		Platform: "B",
-		Name:     "Dover",
-	},
-	Station{
-		Code:     "EW23",
-		Code3:    "CLE",
-		Platform: "B",
-		Name:     "Clementi",
-	},
-	Station{
-		Code:     "EW24",
-		Code3:    "JUR",
-		Platform: "F",
-		Name:     "Jurong East",
-	},
-	Station{
-		Code:     "EW25",
-		Code3:    "CNG",
-		Platform: "B",
-		Name:     "Chinese Garden",
-	},
-	Station{
-		Code:     "EW26",
-		Code3:    "LKS",
-		Platform: "B",
-		Name:     "Lakeside",
-	},
-	Station{
-		Code:     "EW27",
-		Code3:    "BNL",
-		Platform: "B",
-		Name:     "Boon Lay",
-	},
-	Station{
-		Code:     "EW28",
-		Code3:    "PNR",
-		Platform: "B",
-		Name:     "Pioneer",
-	},
-	Station{
-		Code:     "EW29",
-		Code3:    "JKN",
-		Platform: "B",
-		Name:     "Joo Koon",
-	},
-	Station{
-		Code:     "EW30",
-		Code3:    "GCL",
-		Platform: "B",
-		Name:     "Gul Circle",
-	},
-	Station{
-		Code:     "EW31",
-		Code3:    "TCR",
-		Platform: "B",
-		Name:     "Tuas Crescent",
-	},
-	Station{
-		Code:     "EW32",
-		Code3:    "TWR",
-		Platform: "B",
-		Name:     "Tuas West Road",
-	},
-	Station{
-		Code:     "EW33",
-		Code3:    "TLK",
-		Platform: "A",
-		Name:     "Tuas Link",
-	},
-}
-
-var EW_2 = Line{
-	Station{
-		Code:     "EW33",
-		Code3:    "TLK",
-		Platform: "A",
-		Name:     "Tuas Link",
-	},
-	Station{
-		Code:     "EW32",
-		Code3:    "TWR",
-		Platform: "A",
-		Name:     "Tuas West Road",
-	},
-	Station{
-		Code:     "EW31",
-		Code3:    "TCR",
-		Platform: "A",
-		Name:     "Tuas Crescent",
-	},
-	Station{
-		Code:     "EW30",
-		Code3:    "GCL",
-		Platform: "A",
-		Name:     "Gul Circle",
-	},
-	Station{
-		Code:     "EW29",
-		Code3:    "JKN",
-		Platform: "A",
-		Name:     "Joo Koon",
-	},
-	Station{
-		Code:     "EW28",
-		Code3:    "PNR",
-		Platform: "A",
-		Name:     "Pioneer",
-	},
-	Station{
-		Code:     "EW27",
-		Code3:    "BNL",
-		Platform: "A",
-		Name:     "Boon Lay",
-	},
-	Station{
-		Code:     "EW26",
-		Code3:    "LKS",
-		Platform: "A",
-		Name:     "Lakeside",
-	},
-	Station{
-		Code:     "EW25",
-		Code3:    "CNG",
-		Platform: "A",
-		Name:     "Chinese Garden",
-	},
-	Station{
-		Code:     "EW24",
-		Code3:    "JUR",
-		Platform: "B",
-		Name:     "Jurong East",
-	},
-	Station{
-		Code:     "EW23",
-		Code3:    "CLE",
-		Platform: "A",
-		Name:     "Clementi",
-	},
-	Station{
-		Code:     "EW22",
-		Code3:    "DVR",
-		Platform: "A",
-		Name:     "Dover",
-	},
-	Station{
-		Code:     "EW21",
-		Code3:    "BNV",
-		Platform: "A",
-		Name:     "Buona Vista",
-	},
-	Station{
-		Code:     "EW20",
-		Code3:    "COM",
-		Platform: "A",
-		Name:     "Commonwealth",
-	},
-	Station{
-		Code:     "EW19",
-		Code3:    "QUE",
-		Platform: "A",
-		Name:     "Queenstown",
-	},
-	Station{
-		Code:     "EW18",
-		Code3:    "RDH",
-		Platform: "A",
-		Name:     "Redhill",
-	},
-	Station{
-		Code:     "EW17",
-		Code3:    "TIB",
-		Platform: "A",
-		Name:     "Tiong Bahru",
-	},
-	Station{
-		Code:     "EW16",
-		Code3:    "OTP",
-		Platform: "A",
-		Name:     "Outram Park",
-	},
-	Station{
-		Code:     "EW15",
-		Code3:    "TPG",
-		Platform: "A",
-		Name:     "Tanjong Pagar",
-	},
-	Station{
-		Code:     "EW14",
-		Code3:    "RFP",
-		Platform: "A",
-		Name:     "Raffles Place",
-	},
-	Station{
-		Code:     "EW13",
-		Code3:    "CTH",
-		Platform: "D",
-		Name:     "City Hall",
-	},
-	Station{
-		Code:     "EW12",
-		Code3:    "BGS",
-		Platform: "A",
-		Name:     "Bugis",
-	},
-	Station{
-		Code:     "EW11",
-		Code3:    "LVR",
-		Platform: "A",
-		Name:     "Lavender",
-	},
-	Station{
-		Code:     "EW10",
-		Code3:    "KAL",
-		Platform: "A",
-		Name:     "Kallang",
-	},
-	Station{
-		Code:     "EW9",
-		Code3:    "ALJ",
-		Platform: "A",
-		Name:     "Aljunied",
-	},
-	Station{
-		Code:     "EW8",
-		Code3:    "PYL",
-		Platform: "A",
-		Name:     "Paya Lebar",
-	},
-	Station{
-		Code:     "EW7",
-		Code3:    "EUN",
-		Platform: "A",
-		Name:     "Eunos",
-	},
-	Station{
-		Code:     "EW6",
-		Code3:    "KEM",
-		Platform: "A",
-		Name:     "Kembangan",
-	},
-	Station{
-		Code:     "EW5",
-		Code3:    "BDK",
-		Platform: "A",
-		Name:     "Bedok",
-	},
-	Station{
-		Code:     "EW4",
-		Code3:    "TNM",
-		Platform: "A",
-		Name:     "Tanah Merah",
-	},
-	Station{
-		Code:     "EW3",
-		Code3:    "SIM",
-		Platform: "A",
-		Name:     "Simei",
-	},
-	Station{
-		Code:     "EW2",
-		Code3:    "TAM",
-		Platform: "A",
-		Name:     "Tampines",
-	},
-	Station{
-		Code:     "EW1",
-		Code3:    "PSR",
-		Platform: "A",
-		Name:     "Pasir Ris",
-	},
-}
-
-var NS_1 = Line{
-	Station{
-		Code:     "NS1",
-		Code3:    "JUR",
-		Platform: "A",
-		Name:     "Jurong East",
-	},
-	Station{
-		Code:     "NS2",
-		Code3:    "BBT",
-		Platform: "B",
-		Name:     "Bukit Batok",
-	},
-	Station{
-		Code:     "NS3",
-		Code3:    "BGB",
-		Platform: "B",
-		Name:     "Bukit Gombak",
-	},
-	Station{
-		Code:     "NS4",
-		Code3:    "CCK",
-		Platform: "B",
-		Name:     "Choa Chu Kang",
-	},
-	Station{
-		Code:     "NS5",
-		Code3:    "YWT",
-		Platform: "B",
-		Name:     "Yew Tee",
-	},
-	Station{
-		Code:     "NS7",
-		Code3:    "KRJ",
-		Platform: "B",
-		Name:     "Kranji",
-	},
-	Station{
-		Code:     "NS8",
-		Code3:    "MSL",
-		Platform: "B",
-		Name:     "Marsiling",
-	},
-	Station{
-		Code:     "NS9",
-		Code3:    "WDL",
-		Platform: "B",
-		Name:     "Woodlands",
-	},
-	Station{
-		Code:     "NS10",
-		Code3:    "ADM",
-		Platform: "B",
-		Name:     "Admiralty",
-	},
-	Station{
-		Code:     "NS11",
-		Code3:    "SBW",
-		Platform: "B",
-		Name:     "Sembawang",
-	},
-	Station{
-		Code:     "NS12",
-		Code3:    "CBR",
-		Platform: "B",
-		Name:     "Canberra",
-	},
-	Station{
-		Code:     "NS13",
-		Code3:    "YIS",
-		Platform: "B",
-		Name:     "Yishun",
-	},
-	Station{
-		Code:     "NS14",
-		Code3:    "KTB",
-		Platform: "B",
-		Name:     "Khatib",
-	},
-	Station{
-		Code:     "NS15",
-		Code3:    "YCK",
-		Platform: "B",
-		Name:     "Yio Chu Kang",
-	},
-	Station{
-		Code:     "NS16",
-		Code3:    "AMK",
-		Platform: "B",
-		Name:     "Ang Mo Kio",
-	},
-	Station{
-		Code:     "NS17",
-		Code3:    "BSH",
-		Platform: "B",
-		Name:     "Bishan",
-	},
-	Station{
-		Code:     "NS18",
-		Code3:    "BDL",
-		Platform: "B",
-		Name:     "Braddell",
-	},
-	Station{
-		Code:     "NS19",
-		Code3:    "TAP",
-		Platform: "B",
-		Name:     "Toa Payoh",
-	},
-	Station{
-		Code:     "NS20",
-		Code3:    "NOV",
-		Platform: "B",
-		Name:     "Novena",
-	},
-	Station{
-		Code:     "NS21",
-		Code3:    "NEW",
-		Platform: "B",
-		Name:     "Newton",
-	},
-	Station{
-		Code:     "NS22",
-		Code3:    "ORC",
-		Platform: "B",
-		Name:     "Orchard",
-	},
-	Station{
-		Code:     "NS23",
-		Code3:    "SOM",
-		Platform: "B",
-		Name:     "Somerset",
-	},
-	Station{
-		Code:     "NS24",
-		Code3:    "DBG",
-		Platform: "B",
-		Name:     "Dhoby Ghaut",
-	},
-	Station{
-		Code:     "NS25",
-		Code3:    "CTH",
-		Platform: "C",
-		Name:     "City Hall",
-	},
-	Station{
-		Code:     "NS26",
-		Code3:    "RFP",
-		Platform: "D",
-		Name:     "Raffles Place",
-	},
-	Station{
-		Code:     "NS27",
-		Code3:    "MRB",
-		Platform: "A",
-		Name:     "Marina Bay",
-	},
-	Station{
-		Code:     "NS28",
-		Code3:    "MSP",
-		Platform: "A",
-		Name:     "Marina South Pier",
-	},
-}
-
-var NS_2 = Line{
-	Station{
-		Code:     "NS28",
-		Code3:    "MSP",
-		Platform: "A",
-		Name:     "Marina South Pier",
-	},
-	Station{
-		Code:     "NS27",
-		Code3:    "MRB",
-		Platform: "A",
-		Name:     "Marina Bay",
-	},
-	Station{
-		Code:     "NS26",
-		Code3:    "RFP",
-		Platform: "B",
-		Name:     "Raffles Place",
-	},
-	Station{
-		Code:     "NS25",
-		Code3:    "CTH",
-		Platform: "A",
-		Name:     "City Hall",
-	},
-	Station{
-		Code:     "NS24",
-		Code3:    "DBG",
-		Platform: "A",
-		Name:     "Dhoby Ghaut",
-	},
-	Station{
-		Code:     "NS23",
-		Code3:    "SOM",
-		Platform: "A",
-		Name:     "Somerset",
-	},
-	Station{
-		Code:     "NS22",
-		Code3:    "ORC",
-		Platform: "A",
-		Name:     "Orchard",
-	},
-	Station{
-		Code:     "NS21",
-		Code3:    "NEW",
-		Platform: "A",
-		Name:     "Newton",
-	},
-	Station{
-		Code:     "NS20",
-		Code3:    "NOV",
-		Platform: "A",
-		Name:     "Novena",
-	},
-	Station{
-		Code:     "NS19",
-		Code3:    "TAP",
-		Platform: "A",
-		Name:     "Toa Payoh",
-	},
-	Station{
-		Code:     "NS18",
-		Code3:    "BDL",
-		Platform: "A",
-		Name:     "Braddell",
-	},
-	Station{
-		Code:     "NS17",
-		Code3:    "BSH",
-		Platform: "A",
-		Name:     "Bishan",
-	},
-	Station{
-		Code:     "NS16",
-		Code3:    "AMK",
-		Platform: "A",
-		Name:     "Ang Mo Kio",
-	},
-	Station{
-		Code:     "NS15",
-		Code3:    "YCK",
-		Platform: "A",
-		Name:     "Yio Chu Kang",
-	},
-	Station{
-		Code:     "NS14",
-		Code3:    "KTB",
-		Platform: "A",
-		Name:     "Khatib",
-	},
-	Station{
-		Code:     "NS13",
-		Code3:    "YIS",
-		Platform: "A",
-		Name:     "Yishun",
-	},
-	Station{
-		Code:     "NS12",
-		Code3:    "CBR",
-		Platform: "A",
-		Name:     "Canberra",
-	},
-	Station{
-		Code:     "NS11",
-		Code3:    "SBW",
-		Platform: "A",
-		Name:     "Sembawang",
-	},
-	Station{
-		Code:     "NS10",
-		Code3:    "ADM",
-		Platform: "A",
-		Name:     "Admiralty",
-	},
-	Station{
-		Code:     "NS9",
-		Code3:    "WDL",
-		Platform: "A",
-		Name:     "Woodlands",
-	},
-	Station{
-		Code:     "NS8",
-		Code3:    "MSL",
-		Platform: "A",
-		Name:     "Marsiling",
-	},
-	Station{
-		Code:     "NS7",
-		Code3:    "KRJ",
-		Platform: "A",
-		Name:     "Kranji",
-	},
-	Station{
-		Code:     "NS5",
-		Code3:    "YWT",
-		Platform: "A",
-		Name:     "Yew Tee",
-	},
-	Station{
-		Code:     "NS4",
-		Code3:    "CCK",
-		Platform: "A",
-		Name:     "Choa Chu Kang",
-	},
-	Station{
-		Code:     "NS3",
-		Code3:    "BGB",
-		Platform: "A",
-		Name:     "Bukit Gombak",
-	},
-	Station{
-		Code:     "NS2",
-		Code3:    "BBT",
-		Platform: "A",
-		Name:     "Bukit Batok",
-	},
-	Station{
-		Code:     "NS1",
-		Code3:    "JUR",
-		Platform: "A",
-		Name:     "Jurong East",
-	},
-}
@@ -0,0 +1,351 @@
+package data
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Line definitions are loaded from lines.json, which is embedded and can be replaced at startup
+// with Load. The format is the graph of the network: every station once, with all its codes,
+// then each line as the stops it makes, by code and platform
+//
+//	{
+//	  "stations": [
+//	    {"codes": ["NS1", "EW24"], "code3": "JUR", "name": "Jurong East", "lat": 1.33315, "lng": 103.74224},
+//	    ...
+//	  ],
+//	  "lines": [
+//	    {
+//	      "name": "ns1",
+//	      "stations": [
+//	        {"code": "NS1", "platform": "A"},
+//	        ...
+//
+// Stops are in the order trains call at them, and name is what the smrt API calls the station.
+// A branch stops at a station by its code on the line it leaves, eg cg1 starts at EW4, so
+// branches and interchanges are stated once, where the station is. A loop lists the station it
+// starts from again at the end. A stop can also be written out in full, with the code3, name
+// and location, for files without a stations list.
+//
+//go:embed lines.json
+var defaultLinesJSON []byte
+
+var (
+	lineNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+	codePattern     = regexp.MustCompile(`^[A-Z]{2}[0-9]*$`)
+	code3Pattern    = regexp.MustCompile(`^[A-Z]{3}$`)
+	platformPattern = regexp.MustCompile(`^[A-Z]$`)
+)
+
+var (
+	linesLock      sync.RWMutex
+	loadedLines    []LineNameDataPair
+	loadedNetwork  *Network
+	loadedIndex    *Index
+	loadedTracks   trackSet
+	loadedRunTimes runTimeSet
+	defaultLines   []LineNameDataPair
+	defaultTracks  []Track
+	defaultRuns    []RunTime
+)
+
+func init() {
+	lines, err := ParseLines(defaultLinesJSON)
+	if err != nil {
+		panic(fmt.Sprintf("embedded lines.json: %v", err))
+	}
+	tracks, err := ParseTracks(defaultLinesJSON, lines)
+	if err != nil {
+		panic(fmt.Sprintf("embedded lines.json: %v", err))
+	}
+	runTimes, err := ParseRunTimes(defaultLinesJSON, lines)
+	if err != nil {
+		panic(fmt.Sprintf("embedded lines.json: %v", err))
+	}
+	network, err := NewNetwork(lines)
+	if err != nil {
+		panic(fmt.Sprintf("embedded lines.json: %v", err))
+	}
+	defaultLines = network.Lines()
+	loadedLines = defaultLines
+	loadedNetwork = network
+	loadedIndex = NewIndex(network)
+	defaultTracks = tracks
+	loadedTracks = newTrackSet(tracks)
+	defaultRuns = runTimes
+	loadedRunTimes = newRunTimeSet(runTimes)
+}
+
+type linesFile struct {
+	Stations []stationEntry `json:"stations,omitempty"`
+	Lines    []lineEntry    `json:"lines"`
+	Tracks   []Track        `json:"tracks,omitempty"`
+	RunTimes []RunTime      `json:"run_times,omitempty"`
+}
+
+// stationEntry is a node of the network, see Node
+type stationEntry struct {
+	Codes []string `json:"codes"`
+	Code3 string   `json:"code3"`
+	Name  string   `json:"name"`
+	Lat   float64  `json:"lat,omitempty"`
+	Lng   float64  `json:"lng,omitempty"`
+}
+
+type lineEntry struct {
+	Name     string `json:"name"`
+	Stations Line   `json:"stations"`
+}
+
+// Load replaces the line set with the one in the file at path. It is meant to be called once at
+// startup, before anything reads the lines. On error, the line set is left as it was.
+func Load(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines, err := ParseLines(b)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	tracks, err := ParseTracks(b, lines)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	runTimes, err := ParseRunTimes(b, lines)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	network, err := NewNetwork(lines)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	index := NewIndex(network)
+
+	// the lines everything uses are walks of the graph
+	linesLock.Lock()
+	loadedLines = network.Lines()
+	loadedNetwork = network
+	loadedIndex = index
+	loadedTracks = newTrackSet(tracks)
+	loadedRunTimes = newRunTimeSet(runTimes)
+	linesLock.Unlock()
+	return nil
+}
+
+// DefaultLines returns the embedded line set, whatever has been loaded since
+func DefaultLines() []LineNameDataPair {
+	return defaultLines
+}
+
+// DefaultTracks returns the embedded track shapes
+func DefaultTracks() []Track {
+	return defaultTracks
+}
+
+// DefaultRunTimes returns the embedded run times
+func DefaultRunTimes() []RunTime {
+	return defaultRuns
+}
+
+// ParseLines reads and validates line definitions in the lines.json format
+func ParseLines(b []byte) ([]LineNameDataPair, error) {
+	var f linesFile
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&f)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.Lines) == 0 {
+		return nil, fmt.Errorf("no lines")
+	}
+
+	byCode := make(map[string]stationEntry)
+	for i, st := range f.Stations {
+		if len(st.Codes) == 0 {
+			return nil, fmt.Errorf("station %d: %s has no codes", i, st.Name)
+		}
+		for _, c := range st.Codes {
+			if _, ok := byCode[c]; ok {
+				return nil, fmt.Errorf("station %d: code %s appears twice", i, c)
+			}
+			byCode[c] = st
+		}
+	}
+
+	seenLines := make(map[string]bool)
+	// the same station must look the same on every line
+	stations := make(map[string]Station)
+
+	out := make([]LineNameDataPair, 0, len(f.Lines))
+	for i, l := range f.Lines {
+		if !lineNamePattern.MatchString(l.Name) {
+			return nil, fmt.Errorf("line %d: invalid name %q", i, l.Name)
+		}
+		if seenLines[l.Name] {
+			return nil, fmt.Errorf("line %s: duplicate name", l.Name)
+		}
+		seenLines[l.Name] = true
+
+		if len(l.Stations) < 2 {
+			return nil, fmt.Errorf("line %s: needs at least 2 stations", l.Name)
+		}
+
+		seenPlatforms := make(map[string]bool)
+		for j, s := range l.Stations {
+			if s.Code3 == "" && s.Name == "" {
+				st, ok := byCode[s.Code]
+				if !ok {
+					return nil, fmt.Errorf("line %s: station %d: %s isn't in the stations", l.Name, j, s.Code)
+				}
+				s.Code3, s.Name, s.Lat, s.Lng = st.Code3, st.Name, st.Lat, st.Lng
+				l.Stations[j] = s
+			}
+			err = validateStation(s)
+			if err != nil {
+				return nil, fmt.Errorf("line %s: station %d: %w", l.Name, j, err)
+			}
+			// a loop ends where it starts, otherwise no platform is used twice
+			closesLoop := j == len(l.Stations)-1 && j > 1 && s.PlatformID() == l.Stations[0].PlatformID()
+			if seenPlatforms[s.PlatformID()] && !closesLoop {
+				return nil, fmt.Errorf("line %s: station %d: platform %s appears twice", l.Name, j, s.PlatformID())
+			}
+			seenPlatforms[s.PlatformID()] = true
+
+			if prev, ok := stations[s.Code]; ok && (prev.Code3 != s.Code3 || prev.Name != s.Name || prev.Location() != s.Location()) {
+				return nil, fmt.Errorf("line %s: station %d: %s is %s %q elsewhere", l.Name, j, s.Code, prev.Code3, prev.Name)
+			}
+			stations[s.Code] = s
+		}
+
+		out = append(out, LineNameDataPair{Name: l.Name, Line: l.Stations})
+	}
+
+	return out, nil
+}
+
+func validateStation(s Station) error {
+	switch {
+	case !codePattern.MatchString(s.Code):
+		return fmt.Errorf("invalid code %q", s.Code)
+	case !code3Pattern.MatchString(s.Code3):
+		return fmt.Errorf("invalid code3 %q", s.Code3)
+	case !platformPattern.MatchString(s.Platform):
+		return fmt.Errorf("invalid platform %q", s.Platform)
+	case s.Name == "":
+		return fmt.Errorf("%s has no name", s.Code)
+	}
+	return nil
+}
+
+// EncodeLines writes lines and track shapes in the lines.json format, one station or track per
+// line of text so that diffs are easy to read.
+func EncodeLines(lines []LineNameDataPair, tracks ...Track) []byte {
+	return EncodeLineData(lines, tracks, nil)
+}
+
+// EncodeLineData is EncodeLines with run times, one per line of text after the tracks
+func EncodeLineData(lines []LineNameDataPair, tracks []Track, runTimes []RunTime) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+
+	// lines that aren't a valid network are written out in full, for ParseLines to complain about
+	network, err := NewNetwork(lines)
+	full := err != nil
+	if !full {
+		buf.WriteString("  \"stations\": [\n")
+		for i, node := range network.Stations() {
+			codes := make([]string, len(node.Codes))
+			for j, c := range node.Codes {
+				codes[j] = jsonString(c)
+			}
+			fmt.Fprintf(&buf, `    {"codes": [%s], "code3": %s, "name": %s`,
+				strings.Join(codes, ", "), jsonString(node.Code3), jsonString(node.Name))
+			if node.Lat != 0 || node.Lng != 0 {
+				fmt.Fprintf(&buf, `, "lat": %s, "lng": %s`, formatCoord(node.Lat), formatCoord(node.Lng))
+			}
+			buf.WriteRune('}')
+			if i < len(network.Stations())-1 {
+				buf.WriteRune(',')
+			}
+			buf.WriteRune('\n')
+		}
+		buf.WriteString("  ],\n")
+	}
+
+	buf.WriteString("  \"lines\": [\n")
+	for i, l := range lines {
+		fmt.Fprintf(&buf, "    {\n      \"name\": %s,\n      \"stations\": [\n", jsonString(l.Name))
+		for j, s := range l.Line {
+			if full {
+				fmt.Fprintf(&buf, `        {"code": %s, "code3": %s, "platform": %s, "name": %s`,
+					jsonString(s.Code), jsonString(s.Code3), jsonString(s.Platform), jsonString(s.Name))
+				if s.HasLocation() {
+					fmt.Fprintf(&buf, `, "lat": %s, "lng": %s`, formatCoord(s.Lat), formatCoord(s.Lng))
+				}
+			} else {
+				fmt.Fprintf(&buf, `        {"code": %s, "platform": %s`, jsonString(s.Code), jsonString(s.Platform))
+			}
+			buf.WriteRune('}')
+			if j < len(l.Line)-1 {
+				buf.WriteRune(',')
+			}
+			buf.WriteRune('\n')
+		}
+		buf.WriteString("      ]\n    }")
+		if i < len(lines)-1 {
+			buf.WriteRune(',')
+		}
+		buf.WriteRune('\n')
+	}
+	buf.WriteString("  ]")
+
+	if len(tracks) > 0 {
+		buf.WriteString(",\n  \"tracks\": [\n")
+		for i, t := range tracks {
+			fmt.Fprintf(&buf, `    {"from": %s, "to": %s, "points": [`, jsonString(t.From), jsonString(t.To))
+			for j, p := range t.Points {
+				if j > 0 {
+					buf.WriteString(", ")
+				}
+				fmt.Fprintf(&buf, "[%s, %s]", formatCoord(p[0]), formatCoord(p[1]))
+			}
+			buf.WriteString("]}")
+			if i < len(tracks)-1 {
+				buf.WriteRune(',')
+			}
+			buf.WriteRune('\n')
+		}
+		buf.WriteString("  ]")
+	}
+
+	if len(runTimes) > 0 {
+		buf.WriteString(",\n  \"run_times\": [\n")
+		for i, r := range runTimes {
+			fmt.Fprintf(&buf, `    {"from": %s, "to": %s, "run": %d, "dwell": %d}`,
+				jsonString(r.From), jsonString(r.To), r.Run, r.Dwell)
+			if i < len(runTimes)-1 {
+				buf.WriteRune(',')
+			}
+			buf.WriteRune('\n')
+		}
+		buf.WriteString("  ]")
+	}
+	buf.WriteString("\n}\n")
+	return buf.Bytes()
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s) // can't fail for a string
+	return string(b)
+}
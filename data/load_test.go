@@ -0,0 +1,93 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLines(t *testing.T) {
+	lines, err := ParseLines(EncodeLines(DefaultLines()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, DefaultLines()) {
+		t.Error("lines changed after encoding and parsing")
+	}
+
+	station := `{"code": "EW1", "code3": "PSR", "platform": "A", "name": "Pasir Ris"}`
+	other := `{"code": "EW2", "code3": "TAM", "platform": "A", "name": "Tampines"}`
+	bad := map[string]string{
+		"no lines":        `{"lines": []}`,
+		"unknown field":   `{"lines": [], "extra": 1}`,
+		"bad line name":   `{"lines": [{"name": "EW 1", "stations": [` + station + `, ` + other + `]}]}`,
+		"one station":     `{"lines": [{"name": "ew1", "stations": [` + station + `]}]}`,
+		"duplicate line":  `{"lines": [{"name": "ew1", "stations": [` + station + `, ` + other + `]}, {"name": "ew1", "stations": [` + station + `, ` + other + `]}]}`,
+		"same platform":   `{"lines": [{"name": "ew1", "stations": [` + station + `, ` + station + `]}]}`,
+		"bad code":        `{"lines": [{"name": "ew1", "stations": [` + strings.Replace(station, "EW1", "E1", 1) + `, ` + other + `]}]}`,
+		"bad platform":    `{"lines": [{"name": "ew1", "stations": [` + strings.Replace(station, `"A"`, `"a"`, 1) + `, ` + other + `]}]}`,
+		"inconsistent":    `{"lines": [{"name": "ew1", "stations": [` + station + `, ` + other + `]}, {"name": "ew2", "stations": [` + other + `, ` + strings.Replace(station, "Pasir Ris", "Pasir Rice", 1) + `]}]}`,
+		"bad run time":    `{"lines": [{"name": "ew1", "stations": [` + station + `, ` + other + `]}], "run_times": [{"from": "EW1", "to": "EW2", "run": 0, "dwell": 0}]}`,
+		"unknown stop":    `{"stations": [{"codes": ["EW1"], "code3": "PSR", "name": "Pasir Ris"}], "lines": [{"name": "ew1", "stations": [{"code": "EW1", "platform": "A"}, {"code": "EW2", "platform": "A"}]}]}`,
+		"code twice":      `{"stations": [{"codes": ["EW1"], "code3": "PSR", "name": "Pasir Ris"}, {"codes": ["EW1"], "code3": "TAM", "name": "Tampines"}], "lines": [{"name": "ew1", "stations": [` + station + `, ` + other + `]}]}`,
+		"missing station": `{"lines": [{"name": "ew1", "stations": [` + station + `, ` + strings.Replace(other, "Tampines", "", 1) + `]}]}`,
+	}
+	for name, src := range bad {
+		lines, err := ParseLines([]byte(src))
+		if err == nil {
+			_, err = ParseRunTimes([]byte(src), lines)
+		}
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// a branch is a stop at a station of the line it leaves
+	src := `{"stations": [{"codes": ["EW4"], "code3": "TNM", "name": "Tanah Merah"}, {"codes": ["CG1"], "code3": "XPO", "name": "Expo"}],
+		"lines": [{"name": "cg1", "stations": [{"code": "EW4", "platform": "C"}, {"code": "CG1", "platform": "A"}]}]}`
+	lines, err = ParseLines([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := lines[0].Line[0]; s != (Station{Code: "EW4", Code3: "TNM", Platform: "C", Name: "Tanah Merah"}) {
+		t.Errorf("got %+v for the branch stop", s)
+	}
+
+	path := filepath.Join(t.TempDir(), "lines.json")
+	err = os.WriteFile(path, []byte(`{"lines": [{"name": "ew1", "stations": [`+station+`, `+other+`]}], "run_times": [{"from": "EW1", "to": "EW2", "run": 90, "dwell": 20}]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		// the network, index and tracks go back too
+		path := filepath.Join(filepath.Dir(path), "default.json")
+		err := os.WriteFile(path, EncodeLineData(DefaultLines(), DefaultTracks(), DefaultRunTimes()), 0644)
+		if err == nil {
+			err = Load(path)
+		}
+		if err != nil {
+			t.Errorf("restoring the line data: %v", err)
+		}
+	})
+	err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(GetLines()) != 1 || !reflect.DeepEqual(GetNames(), []string{"Pasir Ris", "Tampines"}) {
+		t.Errorf("unexpected lines after load %+v", GetLines())
+	}
+	if _, ok := GetLine("ns1"); ok {
+		t.Error("expected ns1 to be gone")
+	}
+
+	l, _ := GetLine("ew1")
+	if r := GetRunTime(l[1], l[0]); r.Run != 90 || r.From != "EW2" {
+		t.Errorf("expected the other direction's run time, got %+v", r)
+	}
+	if r := GetRunTime(l[0], Station{Code: "EW3"}); r.Run != DefaultRunTime.Run {
+		t.Errorf("expected the default run time, got %+v", r)
+	}
+}
@@ -1,21 +1,19 @@
 package data
 
+// GetNames returns the name of every station on the loaded lines, as the smrt API knows them
 func GetNames() []string {
-	// TODO: This can probably be generated once
 	nameSet := make(map[string]struct{})
-	lineSource := []Line{EW_1, NS_1, CG_1}
+	var names []string
 
-	for _, l := range lineSource {
-		for i := range l {
-			nameSet[l[i].Name] = struct{}{}
+	for _, l := range GetLines() {
+		for i := range l.Line {
+			if _, ok := nameSet[l.Line[i].Name]; !ok {
+				nameSet[l.Line[i].Name] = struct{}{}
+				names = append(names, l.Line[i].Name)
+			}
 		}
 	}
 
-	var names []string
-	for k := range nameSet {
-		names = append(names, k)
-	}
-
 	return names
 }
 
@@ -24,13 +22,20 @@ type LineNameDataPair struct {
 	Line Line
 }
 
+// GetLines returns the loaded lines, in the order they were defined.
+// The result is shared, don't modify it.
 func GetLines() []LineNameDataPair {
-	return []LineNameDataPair{
-		{"ns1", NS_1},
-		{"ns2", NS_2},
-		{"ew1", EW_1},
-		{"ew2", EW_2},
-		{"cg1", CG_1},
-		{"cg2", CG_2},
+	linesLock.RLock()
+	defer linesLock.RUnlock()
+	return loadedLines
+}
+
+// GetLine returns the loaded line with the given name
+func GetLine(name string) (Line, bool) {
+	for _, l := range GetLines() {
+		if l.Name == name {
+			return l.Line, true
+		}
 	}
+	return nil, false
 }
@@ -0,0 +1,311 @@
+package data
+
+import "fmt"
+
+// Network is the rail network as a graph. Stations are the nodes, and each line is a chain of
+// stops, one per station it calls at, so the edges are directed and belong to one line and
+// direction. The lines themselves are walks of the graph. A loop's last stop leads back to its
+// first, so it has no ends. lines.json is written as this graph, each station once and each
+// line as its stops, so a branch or interchange is only stated where its station is.
+type Network struct {
+	stations []*Node
+	byName   map[string]*Node
+	// first stop of each line, and the line names in the order they were defined
+	lines     map[string]*Stop
+	lineNames []string
+	loops     map[string]bool
+}
+
+// Node is a station, with every code it has on every line
+type Node struct {
+	Name  string
+	Code3 string
+	// Location, both 0 if unknown
+	Lat, Lng float64
+	// Codes in the order the lines reach them, eg NS1 then EW24 for Jurong East
+	Codes []string
+	// Stops of every line that calls here
+	Stops []*Stop
+}
+
+// Stop is a line calling at a station
+type Stop struct {
+	Station *Node
+	Line    string
+	// The code the line shows for the station. Where a branch leaves a line, this is the
+	// station's code on the line it leaves, eg EW4 for Tanah Merah on cg1.
+	Code     string
+	Platform string
+	// The stop after this one, nil at the end of the line. On a loop the last stop is
+	// followed by the first.
+	Next *Stop
+}
+
+// AsStation returns the stop as it appears in a Line
+func (s *Stop) AsStation() Station {
+	return Station{
+		Code:     s.Code,
+		Code3:    s.Station.Code3,
+		Platform: s.Platform,
+		Name:     s.Station.Name,
+		Lat:      s.Station.Lat,
+		Lng:      s.Station.Lng,
+	}
+}
+
+// Edge is a train going from one station to the next on a line
+type Edge struct {
+	Line     string
+	From, To *Node
+}
+
+// Branch is where a line starts or ends at a station on another line, like the CG branch at
+// Tanah Merah on the EW line, or where a loop leaves the line that brings trains to it, like
+// the Bukit Panjang LRT loop at Bukit Panjang
+type Branch struct {
+	Station *Node
+	// The branch line
+	Line string
+	// Lines the branch leaves: the ones the station's code belongs to, or for a loop, the
+	// lines that aren't loops calling at the station
+	Trunk []string
+}
+
+// NewNetwork builds the graph from lines in the lines.json format. Stations are joined by name.
+func NewNetwork(lines []LineNameDataPair) (*Network, error) {
+	n := &Network{
+		byName: make(map[string]*Node),
+		lines:  make(map[string]*Stop),
+		loops:  make(map[string]bool),
+	}
+
+	for _, l := range lines {
+		if _, ok := n.lines[l.Name]; ok {
+			return nil, fmt.Errorf("line %s: duplicate name", l.Name)
+		}
+		if len(l.Line) == 0 {
+			return nil, fmt.Errorf("line %s: no stations", l.Name)
+		}
+
+		stations := l.Line
+		if l.Line.Loop() {
+			// the last station is the first again
+			stations = stations[:len(stations)-1]
+			n.loops[l.Name] = true
+		}
+
+		var prev *Stop
+		for _, s := range stations {
+			node, ok := n.byName[s.Name]
+			if !ok {
+				node = &Node{Name: s.Name, Code3: s.Code3, Lat: s.Lat, Lng: s.Lng}
+				n.byName[s.Name] = node
+				n.stations = append(n.stations, node)
+			} else if node.Code3 != s.Code3 {
+				return nil, fmt.Errorf("line %s: %s is %s elsewhere", l.Name, s.Name, node.Code3)
+			}
+			if !contains(node.Codes, s.Code) {
+				node.Codes = append(node.Codes, s.Code)
+			}
+
+			stop := &Stop{Station: node, Line: l.Name, Code: s.Code, Platform: s.Platform}
+			node.Stops = append(node.Stops, stop)
+			if prev == nil {
+				n.lines[l.Name] = stop
+			} else {
+				prev.Next = stop
+			}
+			prev = stop
+		}
+		if n.loops[l.Name] {
+			prev.Next = n.lines[l.Name]
+		}
+		n.lineNames = append(n.lineNames, l.Name)
+	}
+
+	return n, nil
+}
+
+// Walk returns the stations of a line in the order trains call at them
+func (n *Network) Walk(line string) (Line, bool) {
+	first, ok := n.lines[line]
+	if !ok {
+		return nil, false
+	}
+	var out Line
+	for _, s := range n.stops(line) {
+		out = append(out, s.AsStation())
+	}
+	if n.loops[line] {
+		out = append(out, first.AsStation())
+	}
+	return out, true
+}
+
+// stops returns each stop of the line once, in order
+func (n *Network) stops(line string) []*Stop {
+	first := n.lines[line]
+	var out []*Stop
+	for s := first; s != nil; s = s.Next {
+		out = append(out, s)
+		if s.Next == first {
+			break
+		}
+	}
+	return out
+}
+
+// Loop reports whether the line is a loop
+func (n *Network) Loop(line string) bool {
+	return n.loops[line]
+}
+
+// Lines walks every line, in the order they were defined
+func (n *Network) Lines() []LineNameDataPair {
+	out := make([]LineNameDataPair, 0, len(n.lineNames))
+	for _, name := range n.lineNames {
+		l, _ := n.Walk(name)
+		out = append(out, LineNameDataPair{Name: name, Line: l})
+	}
+	return out
+}
+
+// Stations returns every station, in the order the lines reach them
+func (n *Network) Stations() []*Node {
+	return n.stations
+}
+
+// Station returns the station with the name the smrt API uses
+func (n *Network) Station(name string) (*Node, bool) {
+	node, ok := n.byName[name]
+	return node, ok
+}
+
+// Edges returns every edge, a line at a time
+func (n *Network) Edges() []Edge {
+	var out []Edge
+	for _, name := range n.lineNames {
+		for _, s := range n.stops(name) {
+			if s.Next != nil {
+				out = append(out, Edge{Line: name, From: s.Station, To: s.Next.Station})
+			}
+		}
+	}
+	return out
+}
+
+// Interchanges returns the stations with codes on more than one line, eg Jurong East NS1/EW24
+func (n *Network) Interchanges() []*Node {
+	var out []*Node
+	for _, node := range n.stations {
+		if node.Interchange() {
+			out = append(out, node)
+		}
+	}
+	return out
+}
+
+// Branches returns where lines start or end at a station coded for a different line
+func (n *Network) Branches() []Branch {
+	var out []Branch
+	for _, name := range n.lineNames {
+		if n.loops[name] {
+			if b, ok := n.loopBranch(name); ok {
+				out = append(out, b)
+			}
+			continue
+		}
+
+		prefix := n.linePrefix(name)
+		stops := n.stops(name)
+		ends := []*Stop{stops[0], stops[len(stops)-1]}
+
+		for _, s := range ends {
+			if codePrefix(s.Code) == prefix {
+				continue
+			}
+			b := Branch{Station: s.Station, Line: name}
+			for _, other := range s.Station.Stops {
+				if n.linePrefix(other.Line) == codePrefix(s.Code) && !contains(b.Trunk, other.Line) {
+					b.Trunk = append(b.Trunk, other.Line)
+				}
+			}
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// loopBranch finds where a loop meets the rest of the network, the station its other lines call at
+func (n *Network) loopBranch(name string) (Branch, bool) {
+	for _, s := range n.stops(name) {
+		b := Branch{Station: s.Station, Line: name}
+		for _, other := range s.Station.Stops {
+			if !n.loops[other.Line] && !contains(b.Trunk, other.Line) {
+				b.Trunk = append(b.Trunk, other.Line)
+			}
+		}
+		if len(b.Trunk) > 0 {
+			return b, true
+		}
+	}
+	return Branch{}, false
+}
+
+// linePrefix is the code prefix most of the line's stations have, eg CG for cg1
+func (n *Network) linePrefix(line string) string {
+	counts := make(map[string]int)
+	best := ""
+	for _, s := range n.stops(line) {
+		p := codePrefix(s.Code)
+		counts[p]++
+		if counts[p] > counts[best] {
+			best = p
+		}
+	}
+	return best
+}
+
+// Interchange reports whether the station has codes on more than one line
+func (node *Node) Interchange() bool {
+	for _, c := range node.Codes[1:] {
+		if codePrefix(c) != codePrefix(node.Codes[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lines returns the names of the lines that call at the station
+func (node *Node) Lines() []string {
+	var out []string
+	for _, s := range node.Stops {
+		if !contains(out, s.Line) {
+			out = append(out, s.Line)
+		}
+	}
+	return out
+}
+
+func codePrefix(code string) string {
+	if len(code) < 2 {
+		return code
+	}
+	return code[:2]
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNetwork returns the graph of the loaded lines. The result is shared, don't modify it.
+func GetNetwork() *Network {
+	linesLock.RLock()
+	defer linesLock.RUnlock()
+	return loadedNetwork
+}
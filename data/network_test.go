@@ -0,0 +1,117 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNetwork(t *testing.T) {
+	n, err := NewNetwork(DefaultLines())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(n.Lines(), DefaultLines()) {
+		t.Error("walks don't match the lines")
+	}
+
+	jur, ok := n.Station("Jurong East")
+	if !ok {
+		t.Fatal("no Jurong East")
+	}
+	if !reflect.DeepEqual(jur.Codes, []string{"NS1", "EW24"}) || !jur.Interchange() {
+		t.Errorf("Jurong East: got codes %v", jur.Codes)
+	}
+	if !reflect.DeepEqual(jur.Lines(), []string{"ns1", "ns2", "ew1", "ew2"}) {
+		t.Errorf("Jurong East: got lines %v", jur.Lines())
+	}
+
+	var names []string
+	for _, s := range n.Interchanges() {
+		names = append(names, s.Name)
+	}
+	for _, name := range []string{"Jurong East", "City Hall", "Raffles Place", "Dhoby Ghaut"} {
+		if !contains(names, name) {
+			t.Errorf("%s is not an interchange: %v", name, names)
+		}
+	}
+	if contains(names, "Tanah Merah") {
+		t.Error("Tanah Merah only has an EW code")
+	}
+
+	if cck, _ := n.Station("Choa Chu Kang"); !cck.Interchange() {
+		t.Errorf("Choa Chu Kang: got codes %v", cck.Codes)
+	}
+
+	// the CG branch and the LRT loops, both ways
+	want := map[string]struct {
+		station string
+		trunk   []string
+	}{
+		"cg1":  {"Tanah Merah", []string{"ew1", "ew2"}},
+		"cg2":  {"Tanah Merah", []string{"ew1", "ew2"}},
+		"bpl1": {"Bukit Panjang", []string{"bp1", "bp2"}},
+		"bpl2": {"Bukit Panjang", []string{"bp1", "bp2"}},
+		"se1":  {"Sengkang", []string{"ne1", "ne2"}},
+		"se2":  {"Sengkang", []string{"ne1", "ne2"}},
+		"sw1":  {"Sengkang", []string{"ne1", "ne2"}},
+		"sw2":  {"Sengkang", []string{"ne1", "ne2"}},
+		"pe1":  {"Punggol", []string{"ne1", "ne2"}},
+		"pe2":  {"Punggol", []string{"ne1", "ne2"}},
+		"pw1":  {"Punggol", []string{"ne1", "ne2"}},
+		"pw2":  {"Punggol", []string{"ne1", "ne2"}},
+	}
+	branches := n.Branches()
+	if len(branches) != len(want) {
+		t.Fatalf("got %d branches, want %d", len(branches), len(want))
+	}
+	for _, b := range branches {
+		w := want[b.Line]
+		if b.Station.Name != w.station || !reflect.DeepEqual(b.Trunk, w.trunk) {
+			t.Errorf("%s: got branch at %s from %v", b.Line, b.Station.Name, b.Trunk)
+		}
+	}
+
+	edges := 0
+	for _, l := range DefaultLines() {
+		edges += len(l.Line) - 1
+	}
+	if len(n.Edges()) != edges {
+		t.Errorf("got %d edges, want %d", len(n.Edges()), edges)
+	}
+}
+
+func TestNetworkLoop(t *testing.T) {
+	st := func(code, code3, platform, name string) Station {
+		return Station{Code: code, Code3: code3, Platform: platform, Name: name}
+	}
+	lines := []LineNameDataPair{
+		{Name: "bp1", Line: Line{st("BP1", "CCK", "C", "Choa Chu Kang"), st("BP2", "SVW", "A", "South View"), st("BP6", "BPJ", "A", "Bukit Panjang")}},
+		{Name: "bpl1", Line: Line{st("BP6", "BPJ", "B", "Bukit Panjang"), st("BP7", "PTR", "A", "Petir"), st("BP8", "PND", "A", "Pending"), st("BP6", "BPJ", "B", "Bukit Panjang")}},
+	}
+	if _, err := ParseLines(EncodeLines(lines)); err != nil {
+		t.Fatal(err)
+	}
+	if !lines[1].Line.Loop() || lines[0].Line.Loop() {
+		t.Error("only bpl1 is a loop")
+	}
+	labels := lines[1].Line.SegmentLabels()
+	if labels[0] != "BP6" || labels[len(labels)-1] != "BP6'" {
+		t.Errorf("got labels %v", labels)
+	}
+
+	n, err := NewNetwork(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(n.Lines(), lines) {
+		t.Error("walks don't match the lines")
+	}
+	if !n.Loop("bpl1") || len(n.Edges()) != 2+3 {
+		t.Errorf("got %d edges", len(n.Edges()))
+	}
+
+	branches := n.Branches()
+	if len(branches) != 1 || branches[0].Station.Name != "Bukit Panjang" || !reflect.DeepEqual(branches[0].Trunk, []string{"bp1"}) {
+		t.Errorf("got branches %+v", branches)
+	}
+}
@@ -0,0 +1,97 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RunTime is how long trains take between two adjacent stations, in one direction. Run times
+// are in the "run_times" list of lines.json, entered by hand or learned from recordings:
+//
+//	"run_times": [
+//	  {"from": "EW1", "to": "EW2", "run": 150, "dwell": 30},
+//
+// Without a run time for a direction, the other direction's is used, and without either the
+// nominal DefaultRunTime.
+type RunTime struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Seconds from leaving From to arriving at To
+	Run int `json:"run"`
+	// Seconds stopped at From before leaving
+	Dwell int `json:"dwell"`
+}
+
+// DefaultRunTime is used between stations without a run time
+var DefaultRunTime = RunTime{Run: 120, Dwell: 30}
+
+// RunDuration is the time from leaving one station to arriving at the next
+func (r RunTime) RunDuration() time.Duration {
+	return time.Duration(r.Run) * time.Second
+}
+
+// DwellDuration is the time stopped at the first station
+func (r RunTime) DwellDuration() time.Duration {
+	return time.Duration(r.Dwell) * time.Second
+}
+
+// ParseRunTimes reads and validates the run times in a file in the lines.json format
+func ParseRunTimes(b []byte, lines []LineNameDataPair) ([]RunTime, error) {
+	var f linesFile
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&f)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, l := range lines {
+		for _, s := range l.Line {
+			known[s.Code] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, r := range f.RunTimes {
+		switch {
+		case !known[r.From] || !known[r.To]:
+			return nil, fmt.Errorf("run time %d: unknown station %s or %s", i, r.From, r.To)
+		case seen[trackKey(r.From, r.To)]:
+			return nil, fmt.Errorf("run time %d: %s-%s appears twice", i, r.From, r.To)
+		case r.Run <= 0 || r.Dwell < 0:
+			return nil, fmt.Errorf("run time %d: %s-%s: invalid run %d or dwell %d", i, r.From, r.To, r.Run, r.Dwell)
+		}
+		seen[trackKey(r.From, r.To)] = true
+	}
+	return f.RunTimes, nil
+}
+
+// runTimeSet is run times by "from-to"
+type runTimeSet map[string]RunTime
+
+func newRunTimeSet(runTimes []RunTime) runTimeSet {
+	out := make(runTimeSet)
+	for _, r := range runTimes {
+		out[trackKey(r.From, r.To)] = r
+	}
+	return out
+}
+
+// GetRunTime returns the run time from one station to the next
+func GetRunTime(from, to Station) RunTime {
+	linesLock.RLock()
+	defer linesLock.RUnlock()
+
+	if r, ok := loadedRunTimes[trackKey(from.Code, to.Code)]; ok {
+		return r
+	}
+	if r, ok := loadedRunTimes[trackKey(to.Code, from.Code)]; ok {
+		return RunTime{From: from.Code, To: to.Code, Run: r.Run, Dwell: r.Dwell}
+	}
+	r := DefaultRunTime
+	r.From, r.To = from.Code, to.Code
+	return r
+}
@@ -7,10 +7,13 @@ import (
 )
 
 type Station struct {
-	Code     string // alphanumeric station code
-	Code3    string // three letter alphabetical station code
-	Platform string // platform letter
-	Name     string
+	Code     string `json:"code"`     // alphanumeric station code
+	Code3    string `json:"code3"`    // three letter alphabetical station code
+	Platform string `json:"platform"` // platform letter
+	Name     string `json:"name"`
+	// Location of the station, both 0 if unknown
+	Lat float64 `json:"lat,omitempty"`
+	Lng float64 `json:"lng,omitempty"`
 }
 
 func (s Station) CodeNum() int {
@@ -58,3 +61,31 @@ func (l Line) Repr() string {
 
 	return sb.String()
 }
+
+// Loop reports whether the line is a loop, like the LRT loops. A loop ends back at the
+// platform it starts from, and that platform is listed at both ends.
+func (l Line) Loop() bool {
+	return len(l) > 2 && l[0].PlatformID() == l[len(l)-1].PlatformID()
+}
+
+// SegmentLabels names each position of the line, in the same order as model.Position.
+// Platforms are labelled with their station code and the track between two platforms with
+// both codes, eg "EW1", "EW1-EW2", "EW2". The end of a loop is the start again and is
+// labelled with a prime, eg "BP6'", so labels stay unique.
+func (l Line) SegmentLabels() []string {
+	if len(l) == 0 {
+		return nil
+	}
+
+	out := make([]string, 0, len(l)*2-1)
+	for i := range l {
+		if i > 0 {
+			out = append(out, l[i-1].Code+"-"+l[i].Code)
+		}
+		out = append(out, l[i].Code)
+	}
+	if l.Loop() {
+		out[len(out)-1] += "'"
+	}
+	return out
+}
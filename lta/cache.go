@@ -0,0 +1,73 @@
+package lta
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTTL = time.Minute
+	// a refresh runs in the background, so it can't use a request's context
+	fetchTimeout = 10 * time.Second
+)
+
+// Cache keeps the last alerts fetched, so every request doesn't go to DataMall. Alerts are
+// refreshed in the background once they are older than the TTL, requests never wait for
+// DataMall. If a refresh fails, the last alerts are kept until a refresh succeeds.
+type Cache struct {
+	Client *Client
+	// How long alerts are used before fetching them again. Defaults to a minute.
+	TTL time.Duration
+
+	lock      sync.Mutex
+	alerts    *ServiceAlerts
+	lines     map[string][]LineAlert
+	fetchedAt time.Time
+	triedAt   time.Time
+	// the refresh in progress, if any
+	wg sync.WaitGroup
+}
+
+// Get returns the alerts, and when they were fetched. Alerts are nil if they have never been
+// fetched successfully, which is the case until the first refresh finishes.
+func (c *Cache) Get(_ context.Context) (*ServiceAlerts, map[string][]LineAlert, time.Time) {
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// failures wait out the ttl too, DataMall rate limits. triedAt is set before the refresh
+	// starts so only one runs at a time.
+	if time.Since(c.triedAt) >= ttl {
+		c.triedAt = time.Now()
+		c.wg.Add(1)
+		go c.refresh(c.triedAt)
+	}
+
+	return c.alerts, c.lines, c.fetchedAt
+}
+
+func (c *Cache) refresh(triedAt time.Time) {
+	defer c.wg.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	alerts, err := c.Client.TrainServiceAlerts(ctx)
+	if err != nil {
+		log.Printf("error: service alerts: %v", err)
+		return
+	}
+
+	lines := alerts.LineAlerts()
+	c.lock.Lock()
+	c.alerts = alerts
+	c.lines = lines
+	c.fetchedAt = triedAt
+	c.lock.Unlock()
+}
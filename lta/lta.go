@@ -0,0 +1,232 @@
+// Package lta is a client for the LTA DataMall TrainServiceAlerts endpoint, the official word
+// on disruptions, mapped onto the tracker's lines.
+package lta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.lepak.sg/mrtracker-backend/data"
+)
+
+const (
+	DefaultBaseURL = "https://datamall2.mytransport.sg/ltaodataservice"
+
+	StatusNormal    = 1
+	StatusDisrupted = 2
+
+	// Direction of a segment affected both ways
+	DirectionBoth = "Both"
+)
+
+// Client calls DataMall with an account key
+type Client struct {
+	// Defaults to DefaultBaseURL
+	BaseURL    string
+	AccountKey string
+	// Defaults to http.DefaultClient
+	Client *http.Client
+}
+
+// ServiceAlerts is the value of a TrainServiceAlerts response
+type ServiceAlerts struct {
+	// StatusNormal or StatusDisrupted
+	Status           int       `json:"Status"`
+	AffectedSegments []Segment `json:"AffectedSegments"`
+	Message          []Message `json:"Message"`
+}
+
+// Segment is a stretch of a line with no or reduced service
+type Segment struct {
+	// DataMall line code, eg "EWL"
+	Line string `json:"Line"`
+	// Terminal station name trains are heading to, or DirectionBoth
+	Direction string `json:"Direction"`
+	// Comma separated station codes
+	Stations string `json:"Stations"`
+	// Comma separated station codes with free bus rides
+	FreePublicBus string `json:"FreePublicBus"`
+	// Comma separated station codes with free shuttle buses
+	FreeMRTShuttle      string `json:"FreeMRTShuttle"`
+	MRTShuttleDirection string `json:"MRTShuttleDirection"`
+}
+
+type Message struct {
+	Content string `json:"Content"`
+	// "2006-01-02 15:04:05" Singapore time
+	CreatedDate string `json:"CreatedDate"`
+}
+
+type response struct {
+	Value ServiceAlerts `json:"value"`
+}
+
+// TrainServiceAlerts fetches the current alerts
+func (c *Client) TrainServiceAlerts(ctx context.Context) (*ServiceAlerts, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(base, "/")+"/TrainServiceAlerts", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("AccountKey", c.AccountKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("datamall: status %d", resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var r response
+	err = json.Unmarshal(b, &r)
+	if err != nil {
+		return nil, fmt.Errorf("datamall: %w", err)
+	}
+	return &r.Value, nil
+}
+
+// Station is the same shape as in alerts from the alerts handler
+type Station struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// LineAlert is an affected segment on one of our lines
+type LineAlert struct {
+	Line string  `json:"line"`
+	From Station `json:"from"`
+	To   Station `json:"to"`
+	// Some stations in the range may be unaffected, these are the ones that are
+	Stations []Station `json:"stations"`
+	FreeBus  bool      `json:"free_bus"`
+	Shuttle  bool      `json:"shuttle"`
+	Messages []string  `json:"messages,omitempty"`
+}
+
+// LineAlerts maps the affected segments onto the loaded lines, keyed by line name. A segment
+// affects a line if the line calls at its stations, in its direction: a line runs towards its
+// last station, so a segment heading to a terminal affects the lines ending there.
+func (a *ServiceAlerts) LineAlerts() map[string][]LineAlert {
+	out := make(map[string][]LineAlert)
+	if a == nil {
+		return out
+	}
+
+	for _, seg := range a.AffectedSegments {
+		affected := stationNames(seg.Stations)
+		bus := stationNames(seg.FreePublicBus)
+		shuttle := stationNames(seg.FreeMRTShuttle)
+		messages := a.messagesFor(seg.Line)
+
+		for _, l := range data.GetLines() {
+			if !seg.heading(l.Line) {
+				continue
+			}
+
+			from, to := -1, -1
+			var stations []Station
+			freeBus, freeShuttle := false, false
+			for i, s := range l.Line {
+				if !affected[s.Name] {
+					continue
+				}
+				if from < 0 {
+					from = i
+				}
+				to = i
+				stations = append(stations, Station{s.Code, s.Name})
+				freeBus = freeBus || bus[s.Name]
+				freeShuttle = freeShuttle || shuttle[s.Name]
+			}
+			if from < 0 || (from == to && len(affected) > 1) {
+				// at most the end of a segment on another line, eg an interchange
+				continue
+			}
+
+			out[l.Name] = append(out[l.Name], LineAlert{
+				Line:     l.Name,
+				From:     Station{l.Line[from].Code, l.Line[from].Name},
+				To:       Station{l.Line[to].Code, l.Line[to].Name},
+				Stations: stations,
+				FreeBus:  freeBus,
+				Shuttle:  freeShuttle,
+				Messages: messages,
+			})
+		}
+	}
+	return out
+}
+
+// heading reports whether trains on l go the segment's direction
+func (seg Segment) heading(l data.Line) bool {
+	if len(l) == 0 {
+		return false
+	}
+	if seg.Direction == "" || strings.EqualFold(seg.Direction, DirectionBoth) {
+		return true
+	}
+	return strings.EqualFold(seg.Direction, l[len(l)-1].Name)
+}
+
+// messagesFor returns the messages that mention the line, or all of them if none do
+func (a *ServiceAlerts) messagesFor(line string) []string {
+	var all, matched []string
+	for _, m := range a.Message {
+		all = append(all, m.Content)
+		if line != "" && strings.Contains(m.Content, line) {
+			matched = append(matched, m.Content)
+		}
+	}
+	if len(matched) > 0 {
+		return matched
+	}
+	return all
+}
+
+// stationNames resolves comma separated station codes to the names used in the line data
+func stationNames(codes string) map[string]bool {
+	out := make(map[string]bool)
+	index := data.GetIndex()
+	for _, code := range strings.Split(codes, ",") {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		// only codes, a name or partial match would be a guess
+		node, err := index.Lookup(code)
+		if err == nil && containsCode(node.Codes, code) {
+			out[node.Name] = true
+		}
+	}
+	return out
+}
+
+func containsCode(codes []string, code string) bool {
+	for _, c := range codes {
+		if strings.EqualFold(c, code) {
+			return true
+		}
+	}
+	return false
+}
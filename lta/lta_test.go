@@ -0,0 +1,111 @@
+package lta
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// fixtureServer serves a recorded response, and counts requests
+func fixtureServer(t *testing.T, fail *bool, requests *int) *httptest.Server {
+	b, err := os.ReadFile("testdata/train_service_alerts.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.URL.Path != "/ltaodataservice/TrainServiceAlerts" || r.Header.Get("AccountKey") != "key" || *fail {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+}
+
+func TestLineAlerts(t *testing.T) {
+	var fail bool
+	var requests int
+	srv := fixtureServer(t, &fail, &requests)
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL + "/ltaodataservice/", AccountKey: "key"}
+	alerts, err := c.TrainServiceAlerts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alerts.Status != StatusDisrupted || len(alerts.AffectedSegments) != 2 {
+		t.Fatalf("got %+v", alerts)
+	}
+
+	lines := alerts.LineAlerts()
+	if len(lines) != 3 {
+		t.Errorf("expected ew2, ns1 and ns2, got %v", lines)
+	}
+
+	// only towards Pasir Ris, in the order ew2 calls at them
+	ew2 := lines["ew2"]
+	if len(ew2) != 1 || ew2[0].From.Code != "EW24" || ew2[0].To.Code != "EW21" || len(ew2[0].Stations) != 4 {
+		t.Errorf("ew2: got %+v", ew2)
+	}
+	if !ew2[0].FreeBus || !ew2[0].Shuttle || len(ew2[0].Messages) != 1 {
+		t.Errorf("ew2: got %+v", ew2[0])
+	}
+	if _, ok := lines["ew1"]; ok {
+		t.Error("ew1 runs away from Pasir Ris")
+	}
+
+	for _, name := range []string{"ns1", "ns2"} {
+		ns := lines[name]
+		if len(ns) != 1 || len(ns[0].Stations) != 3 || ns[0].FreeBus {
+			t.Errorf("%s: got %+v", name, ns)
+		}
+	}
+
+	c.AccountKey = "wrong"
+	if _, err = c.TrainServiceAlerts(context.Background()); err == nil {
+		t.Error("expected an error with the wrong key")
+	}
+}
+
+func TestCache(t *testing.T) {
+	var fail bool
+	var requests int
+	srv := fixtureServer(t, &fail, &requests)
+	defer srv.Close()
+
+	c := &Cache{
+		Client: &Client{BaseURL: srv.URL + "/ltaodataservice", AccountKey: "key"},
+		TTL:    time.Hour,
+	}
+
+	// the first refresh runs in the background
+	alerts, _, _ := c.Get(context.Background())
+	if alerts != nil {
+		t.Fatal("expected no alerts before the first refresh")
+	}
+	c.wg.Wait()
+
+	alerts, lines, fetched := c.Get(context.Background())
+	if alerts == nil || len(lines["ew2"]) != 1 || fetched.IsZero() {
+		t.Fatal("expected alerts")
+	}
+	c.wg.Wait()
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1 within the ttl", requests)
+	}
+
+	// a failed refresh keeps the last alerts
+	fail = true
+	c.lock.Lock()
+	c.triedAt = time.Time{}
+	c.lock.Unlock()
+	c.Get(context.Background())
+	c.wg.Wait()
+	alerts, _, again := c.Get(context.Background())
+	if requests != 2 || alerts == nil || !again.Equal(fetched) {
+		t.Errorf("got %d requests, alerts %v, fetched %v", requests, alerts != nil, again)
+	}
+}
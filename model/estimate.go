@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+)
+
+// Progress is how far along each position of a line its train is, from 0 at the station it
+// left to 1 at the next one, or UnknownProgress. Stations, and positions without a train, are 0.
+type Progress []float64
+
+// UnknownProgress is the progress of a train between stations that can't be worked out
+const UnknownProgress = -1
+
+// Estimate is ToPositionFor, and also works out how far along the track each train between
+// stations is, from its ETA at the next station and the run time from src
+func (l Line) Estimate(src data.Line) (Position, Progress) {
+	pos := l.ToPositionFor(src)
+	progress := make(Progress, len(pos))
+
+	for i := 1; i < len(l) && i < len(src); i++ {
+		if !pos[i*2-1] {
+			continue
+		}
+		progress[i*2-1] = UnknownProgress
+		if l[i].Next <= 0 {
+			continue
+		}
+		run := data.GetRunTime(src[i-1], src[i]).RunDuration()
+		eta := time.Duration(l[i].Next) * time.Minute
+		// a train due in longer than the run time may not have left yet, or been held up
+		if eta >= run {
+			continue
+		}
+		progress[i*2-1] = 1 - float64(eta)/float64(run)
+	}
+
+	return pos, progress
+}
+
+func (p Progress) Copy() Progress {
+	pp := make(Progress, len(p))
+	copy(pp, p)
+	return pp
+}
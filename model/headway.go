@@ -0,0 +1,64 @@
+package model
+
+import (
+	"math"
+	"sort"
+)
+
+// Headways returns the gap in minutes between the next and subsequent train at every platform,
+// or -1 where either time is unknown. Platforms missing from the results are left at the zero
+// Platform by smrt.ToModel, and no board shows two trains arriving at once, so those are
+// unknown too.
+func (l Line) Headways() []int {
+	out := make([]int, len(l))
+
+	for i := range l {
+		if l[i] == (Platform{}) || l[i].Next < 0 || l[i].Subseq < 0 || l[i].Subseq < l[i].Next {
+			out[i] = -1
+			continue
+		}
+		out[i] = l[i].Subseq - l[i].Next
+	}
+
+	return out
+}
+
+type HeadwayStats struct {
+	// Number of platforms with a known headway, if 0 the rest are NaN
+	Count int
+	Min   float64
+	P50   float64
+	P90   float64
+	Max   float64
+}
+
+// SummarizeHeadways computes line-wide statistics over the output of Headways, ignoring unknowns.
+func SummarizeHeadways(h []int) HeadwayStats {
+	known := make([]float64, 0, len(h))
+	for _, v := range h {
+		if v >= 0 {
+			known = append(known, float64(v))
+		}
+	}
+	sort.Float64s(known)
+
+	return HeadwayStats{
+		Count: len(known),
+		Min:   percentile(known, 0),
+		P50:   percentile(known, 0.5),
+		P90:   percentile(known, 0.9),
+		Max:   percentile(known, 1),
+	}
+}
+
+// percentile interpolates linearly between the closest ranks. sorted must be in ascending order.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+
+	rank := p * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
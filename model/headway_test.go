@@ -0,0 +1,38 @@
+package model
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLine_Headways(t *testing.T) {
+	l := Line{
+		{Next: 0, Subseq: 4},
+		{Next: 2, Subseq: 7},
+		{Next: -1, Subseq: 3},
+		{Next: 3, Subseq: -1},
+		{Next: 6, Subseq: 2}, // bogus
+		{Next: 1, Subseq: 10},
+		{}, // no data
+	}
+
+	h := l.Headways()
+	if expected := []int{4, 5, -1, -1, -1, 9, -1}; !reflect.DeepEqual(h, expected) {
+		t.Errorf("expected %v, got %v", expected, h)
+	}
+
+	s := SummarizeHeadways(h)
+	expected := HeadwayStats{Count: 3, Min: 4, P50: 5, P90: 8.2, Max: 9}
+	if s.Count != expected.Count || s.Min != expected.Min || s.P50 != expected.P50 ||
+		math.Abs(s.P90-expected.P90) > 1e-9 || s.Max != expected.Max {
+		t.Errorf("expected %+v, got %+v", expected, s)
+	}
+}
+
+func TestSummarizeHeadways_Empty(t *testing.T) {
+	s := SummarizeHeadways([]int{-1, -1})
+	if s.Count != 0 || !math.IsNaN(s.P50) {
+		t.Errorf("expected no stats, got %+v", s)
+	}
+}
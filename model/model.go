@@ -1,17 +1,26 @@
 package model
 
-import "errors"
+import (
+	"errors"
+
+	"go.lepak.sg/mrtracker-backend/data"
+)
 
 type Platform struct {
-	// 0: Arr
+	// 0: Arr, -1: unknown
 	Next int
 	Dest string
+	// Train after the next one, same encoding as Next
+	Subseq int
 }
 
 type Line []Platform
 
 type Position []bool
 
+// Occupancy is the probability of a train being at each position of a line
+type Occupancy []float64
+
 func (l Line) ToPosition() Position {
 	pos := make([]bool, len(l)*2-1)
 
@@ -39,6 +48,24 @@ func (l Line) ToPosition() Position {
 	return pos
 }
 
+// ToLoopPosition is ToPosition for a loop, where the last platform is the first one again.
+// The track into the last platform is worked out from the station before it as usual, which
+// is the track into the first, and a train at the platform is only shown at the start.
+func (l Line) ToLoopPosition() Position {
+	pos := l.ToPosition()
+	pos[len(pos)-1] = false
+	return pos
+}
+
+// ToPositionFor is ToPosition or ToLoopPosition, whichever suits src, the line l has the
+// arrivals of
+func (l Line) ToPositionFor(src data.Line) Position {
+	if src.Loop() {
+		return l.ToLoopPosition()
+	}
+	return l.ToPosition()
+}
+
 func (p Position) ToString() string {
 	s := make([]byte, len(p))
 
@@ -84,3 +111,14 @@ func NewPositionFromString(s string) (Position, error) {
 
 	return p, nil
 }
+
+// ToPosition puts a train wherever the probability is at least threshold
+func (o Occupancy) ToPosition(threshold float64) Position {
+	p := make(Position, len(o))
+
+	for i := range o {
+		p[i] = o[i] >= threshold
+	}
+
+	return p
+}
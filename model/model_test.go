@@ -0,0 +1,78 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.lepak.sg/mrtracker-backend/data"
+)
+
+func TestToLoopPosition(t *testing.T) {
+	// loops of three stations, the first listed again at the end
+	cases := []struct {
+		l           Line
+		plain, loop string
+	}{
+		// a train at the first station is shown there once
+		{Line{{Next: 0}, {Next: 3}, {Next: 1}, {Next: 0}}, "*__*__*", "*__*___"},
+		// a train on its way back round is on the track into the end
+		{Line{{Next: 4}, {Next: 0}, {Next: 6}, {Next: 4}}, "__*__*_", "__*__*_"},
+	}
+
+	for _, c := range cases {
+		if got := c.l.ToPosition().ToString(); got != c.plain {
+			t.Errorf("ToPosition: got %s, want %s", got, c.plain)
+		}
+		if got := c.l.ToLoopPosition().ToString(); got != c.loop {
+			t.Errorf("ToLoopPosition: got %s, want %s", got, c.loop)
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	src := data.DefaultLines()[0].Line[:4]
+	runTimes := []data.RunTime{
+		{From: src[0].Code, To: src[1].Code, Run: 240, Dwell: 30},
+		{From: src[2].Code, To: src[3].Code, Run: 180, Dwell: 30},
+	}
+	loadLines(t, data.EncodeLineData([]data.LineNameDataPair{{Name: "ns1", Line: src}}, nil, runTimes))
+
+	l := Line{{Next: 0}, {Next: 1}, {Next: 5}, {Next: 4}}
+	pos, progress := l.Estimate(src)
+	if got := pos.ToString(); got != "**___*_" {
+		t.Errorf("got %s", got)
+	}
+	// a minute out of 4, and 4 minutes away on a 3 minute run
+	want := Progress{0, 0.75, 0, 0, 0, UnknownProgress, 0}
+	if !reflect.DeepEqual(progress, want) {
+		t.Errorf("got %v, want %v", progress, want)
+	}
+}
+
+// loadLines loads line data for the test, and the built in line data again after it
+func loadLines(t *testing.T, b []byte) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "lines.json")
+	err := os.WriteFile(path, b, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = data.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		path := filepath.Join(dir, "default.json")
+		err := os.WriteFile(path, data.EncodeLineData(data.DefaultLines(), data.DefaultTracks(), data.DefaultRunTimes()), 0644)
+		if err == nil {
+			err = data.Load(path)
+		}
+		if err != nil {
+			t.Errorf("restoring the line data: %v", err)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"fmt"
 
+	"go.lepak.sg/mrtracker-backend/data"
 	"go.lepak.sg/mrtracker-backend/data/boards"
 )
 
@@ -19,18 +20,26 @@ func PackBoardV1(ps map[string]Position) ([][]byte, error) {
 
 	var out [3][16]byte
 
-	for name, specs := range boards.DevV1 {
+	for name, specs := range boards.DevV1Segments {
+		line, ok := data.GetLine(name)
+		if !ok {
+			// not loaded, leave it dark
+			continue
+		}
+
 		p, ok := ps[name]
 		if !ok {
 			return nil, fmt.Errorf("name not found in positions: %s", name)
 		}
 
-		if len(specs) != len(p) {
-			return nil, fmt.Errorf("length mismatch: %s: len(specs)=%d len(p)=%d", name, len(specs), len(p))
+		labels := line.SegmentLabels()
+		if len(labels) != len(p) {
+			return nil, fmt.Errorf("length mismatch: %s: len(segments)=%d len(p)=%d", name, len(labels), len(p))
 		}
 
-		for i, spec := range specs {
-			if p[i] {
+		for i, label := range labels {
+			spec, ok := specs[label]
+			if ok && p[i] {
 				// Turn on one bit in the output depending on where the spec says it should be
 				byteOff := (spec.Grid-1)*2 + (spec.Seg-1)/8
 				bitOff := (spec.Seg - 1) % 8
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"testing"
+
+	"go.lepak.sg/mrtracker-backend/data"
+)
+
+func TestPackBoardV1(t *testing.T) {
+	all := make(map[string]Position)
+	for _, l := range data.GetLines() {
+		p := make(Position, len(l.Line)*2-1)
+		for i := range p {
+			p[i] = true
+		}
+		all[l.Name] = p
+	}
+
+	packed, err := PackBoardV1(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every LED on, segments 1 to 10 of every grid
+	for chip := range packed {
+		for i, b := range packed[chip] {
+			if (i%2 == 0 && b != 0xff) || (i%2 == 1 && b != 0x03) {
+				t.Errorf("chip %d byte %d: %08b", chip, i, b)
+			}
+		}
+	}
+
+	one := make(map[string]Position)
+	for _, l := range data.GetLines() {
+		one[l.Name] = make(Position, len(l.Line)*2-1)
+	}
+	one["ns1"][0] = true // JUR, chip 1 grid 4 seg 10
+	packed, err = PackBoardV1(one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for chip := range packed {
+		for i, b := range packed[chip] {
+			expected := byte(0)
+			if chip == 1 && i == 7 {
+				expected = 0x02
+			}
+			if b != expected {
+				t.Errorf("chip %d byte %d: %08b", chip, i, b)
+			}
+		}
+	}
+
+	one["ns1"] = one["ns1"][1:]
+	_, err = PackBoardV1(one)
+	if err == nil {
+		t.Error("expected a short position to fail")
+	}
+}
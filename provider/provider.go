@@ -0,0 +1,92 @@
+// Package provider abstracts where arrivals come from, so the tracker can follow lines run by
+// different operators.
+package provider
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+// Provider gets the next trains at every platform of a set of stations.
+//
+// Arrivals are keyed by station name and normalised to the shape the smrt API uses, which is
+// what the rest of the tracker (smrt.ToModel, the archive, publishers) understands. Platform IDs
+// must match the ones in the line data. A station the provider has nothing for is left out.
+// The count is the number of upstream requests made, for metrics.
+type Provider interface {
+	Arrivals(ctx context.Context, stations []string) (map[string]smrt.Result, int64, error)
+}
+
+var _ Provider = smrt.Provider{}
+
+// Route sends each station to the providers for its lines, picked by station code prefix.
+// An interchange served by more than one provider gets the platforms from all of them.
+// Providers are told apart with ==, so use pointers or plain structs. A provider that fails is
+// logged and its stations are left out, which smrt.ToModel shows as no trains. It is only an
+// error if every provider fails.
+type Route struct {
+	// For stations with a code not in Prefixes, and stations that aren't in the line data
+	Default Provider
+	// By code prefix, eg "NE"
+	Prefixes map[string]Provider
+}
+
+func (r Route) Arrivals(ctx context.Context, stations []string) (map[string]smrt.Result, int64, error) {
+	network := data.GetNetwork()
+
+	var order []Provider
+	batches := make(map[Provider][]string)
+	add := func(p Provider, station string) {
+		if p == nil {
+			return
+		}
+		if _, ok := batches[p]; !ok {
+			order = append(order, p)
+		}
+		if list := batches[p]; len(list) == 0 || list[len(list)-1] != station {
+			batches[p] = append(list, station)
+		}
+	}
+
+	for _, station := range stations {
+		node, ok := network.Station(station)
+		if !ok {
+			add(r.Default, station)
+			continue
+		}
+		for _, code := range node.Codes {
+			p, ok := r.Prefixes[strings.ToUpper(code[:2])]
+			if !ok {
+				p = r.Default
+			}
+			add(p, station)
+		}
+	}
+
+	out := make(map[string]smrt.Result)
+	var total int64
+	var lastErr error
+	failed := 0
+	for _, p := range order {
+		results, tries, err := p.Arrivals(ctx, batches[p])
+		total += tries
+		if err != nil {
+			// the other lines can still be shown
+			log.Printf("error: %T provider for %d stations: %v", p, len(batches[p]), err)
+			lastErr = err
+			failed++
+			continue
+		}
+		for name, res := range results {
+			out[name] = append(out[name], res...)
+		}
+	}
+	if failed > 0 && failed == len(order) {
+		return nil, total, lastErr
+	}
+	return out, total, nil
+}
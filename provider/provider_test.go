@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+// fake answers with one platform per station, named after itself
+type fake struct {
+	platform string
+	asked    []string
+	err      error
+}
+
+func (f *fake) Arrivals(_ context.Context, stations []string) (map[string]smrt.Result, int64, error) {
+	f.asked = append(f.asked, stations...)
+	if f.err != nil {
+		return nil, 1, f.err
+	}
+	out := make(map[string]smrt.Result)
+	for _, s := range stations {
+		out[s] = smrt.Result{{Mrt: s, PlatformID: f.platform}}
+	}
+	return out, 1, nil
+}
+
+func TestRoute(t *testing.T) {
+	ns, ew := &fake{platform: "NS_A"}, &fake{platform: "EW_A"}
+	r := Route{Default: ns, Prefixes: map[string]Provider{"EW": ew}}
+
+	results, tries, err := r.Arrivals(context.Background(), []string{"Jurong East", "Yew Tee", "Tampines", "Nowhere"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tries != 2 {
+		t.Errorf("got %d tries, want one per provider", tries)
+	}
+
+	if !reflect.DeepEqual(ns.asked, []string{"Jurong East", "Yew Tee", "Nowhere"}) {
+		t.Errorf("default was asked for %v", ns.asked)
+	}
+	if !reflect.DeepEqual(ew.asked, []string{"Jurong East", "Tampines"}) {
+		t.Errorf("EW was asked for %v", ew.asked)
+	}
+
+	// the interchange gets platforms from both
+	var ids []string
+	for _, p := range results["Jurong East"] {
+		ids = append(ids, p.PlatformID)
+	}
+	sort.Strings(ids)
+	if !reflect.DeepEqual(ids, []string{"EW_A", "NS_A"}) {
+		t.Errorf("Jurong East: got platforms %v", ids)
+	}
+}
+
+func TestRouteFailure(t *testing.T) {
+	ns, ew := &fake{platform: "NS_A"}, &fake{platform: "EW_A", err: errors.New("down")}
+	r := Route{Default: ns, Prefixes: map[string]Provider{"EW": ew}}
+
+	results, tries, err := r.Arrivals(context.Background(), []string{"Jurong East", "Tampines"})
+	if err != nil {
+		t.Fatalf("expected the default provider's results, got %v", err)
+	}
+	if tries != 2 {
+		t.Errorf("got %d tries, want one per provider", tries)
+	}
+	if len(results["Jurong East"]) != 1 || results["Jurong East"][0].PlatformID != "NS_A" {
+		t.Errorf("Jurong East: got %+v", results["Jurong East"])
+	}
+	if _, ok := results["Tampines"]; ok {
+		t.Errorf("Tampines is only on the failed provider, got %+v", results["Tampines"])
+	}
+
+	ns.err = errors.New("also down")
+	_, _, err = r.Arrivals(context.Background(), []string{"Jurong East", "Tampines"})
+	if err == nil {
+		t.Error("expected an error when every provider fails")
+	}
+}
+
+func TestRouteFailedLine(t *testing.T) {
+	var ew1 data.Line
+	for _, l := range data.GetLines() {
+		if l.Name == "ew1" {
+			ew1 = l.Line
+		}
+	}
+	var stations []string
+	for _, s := range ew1 {
+		stations = append(stations, s.Name)
+	}
+
+	ns, ew := &fake{platform: "NS_A"}, &fake{err: errors.New("down")}
+	r := Route{Default: ns, Prefixes: map[string]Provider{"EW": ew}}
+	results, _, err := r.Arrivals(context.Background(), stations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the line's stations are missing, not trains at every platform
+	for i, p := range smrt.ToModel(results, ew1).ToPositionFor(ew1) {
+		if p {
+			t.Errorf("ew1: got a train at position %d of the failed line", i)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package recorder
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+const (
+	archiveDateFormat = "2006-01-02"
+	archiveExt        = ".json.gz"
+)
+
+// Archive keeps the raw upstream results for every tick, so positions can be recomputed
+// when the inference improves. Each tick is one gzipped JSON file of station name -> results,
+// named after its unix millisecond time, in a directory per local date:
+//
+//	<dir>/2021-09-06/1630886400000.json.gz
+type Archive struct {
+	dir string
+}
+
+func NewArchive(dir string) (*Archive, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &Archive{dir: dir}, nil
+}
+
+// Put writes the results for one tick. The file only appears once it is complete.
+func (a *Archive) Put(t time.Time, raw map[string]smrt.Result) error {
+	dayDir := filepath.Join(a.dir, t.Format(archiveDateFormat))
+	err := os.MkdirAll(dayDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dayDir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// no-op once renamed
+		_ = os.Remove(f.Name())
+	}()
+
+	zw := gzip.NewWriter(f)
+	err = json.NewEncoder(zw).Encode(raw)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	err = zw.Close()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	name := strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10) + archiveExt
+	return os.Rename(f.Name(), filepath.Join(dayDir, name))
+}
+
+// Range calls fn in time order for every tick archived from (inclusive) to (exclusive).
+// If fn returns an error, Range stops and returns it.
+func (a *Archive) Range(from, to time.Time, fn func(t time.Time, raw map[string]smrt.Result) error) error {
+	fromMs := from.UnixNano() / int64(time.Millisecond)
+	toMs := to.UnixNano() / int64(time.Millisecond)
+
+	// date directories are local, so go a day either side in case the location changed
+	for day := from.AddDate(0, 0, -1); day.Before(to.AddDate(0, 0, 1)); day = day.AddDate(0, 0, 1) {
+		dayDir := filepath.Join(a.dir, day.Format(archiveDateFormat))
+		entries, err := os.ReadDir(dayDir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		var times []int64
+		for _, e := range entries {
+			if !strings.HasSuffix(e.Name(), archiveExt) {
+				continue
+			}
+			ms, err := strconv.ParseInt(strings.TrimSuffix(e.Name(), archiveExt), 10, 64)
+			if err != nil || ms < fromMs || ms >= toMs {
+				continue
+			}
+			times = append(times, ms)
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+		for _, ms := range times {
+			raw, err := readArchived(filepath.Join(dayDir, strconv.FormatInt(ms, 10)+archiveExt))
+			if err != nil {
+				return err
+			}
+			err = fn(time.Unix(0, ms*int64(time.Millisecond)), raw)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func readArchived(path string) (map[string]smrt.Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defer zr.Close()
+
+	var raw map[string]smrt.Result
+	err = json.NewDecoder(zr).Decode(&raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return raw, nil
+}
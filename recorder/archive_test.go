@@ -0,0 +1,47 @@
+package recorder
+
+import (
+	"testing"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+func TestArchive(t *testing.T) {
+	a, err := NewArchive(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// crosses midnight
+	start := time.Date(2021, 9, 6, 23, 59, 0, 0, time.Local)
+	for i := 0; i < 4; i++ {
+		err = a.Put(start.Add(time.Duration(i)*30*time.Second), map[string]smrt.Result{
+			"Expo": {{Mrt: "Expo", PlatformID: "XPO_A", NextTrainArr: "Arr"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []time.Time
+	err = a.Range(start.Add(30*time.Second), start.Add(2*time.Minute), func(tm time.Time, raw map[string]smrt.Result) error {
+		if raw["Expo"][0].PlatformID != "XPO_A" {
+			t.Errorf("bad result %+v", raw)
+		}
+		got = append(got, tm)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 ticks, got %v", got)
+	}
+	for i := range got {
+		if !got[i].Equal(start.Add(time.Duration(i+1) * 30 * time.Second)) {
+			t.Errorf("tick %d: got %s", i, got[i])
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package recorder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.lepak.sg/mrtracker-backend/model"
+)
+
+// EncodeArrivals packs the next and subsequent arrival minutes at every station of a line,
+// in line order, as "next/subseq" separated by commas, eg "0/4,2/7,-1/-1".
+// Destinations are not kept.
+func EncodeArrivals(l model.Line) string {
+	var sb strings.Builder
+	for i := range l {
+		if i > 0 {
+			sb.WriteRune(',')
+		}
+		sb.WriteString(strconv.Itoa(l[i].Next))
+		sb.WriteRune('/')
+		sb.WriteString(strconv.Itoa(l[i].Subseq))
+	}
+	return sb.String()
+}
+
+// DecodeArrivals is the reverse of EncodeArrivals
+func DecodeArrivals(s string) (model.Line, error) {
+	if s == "" {
+		return model.Line{}, nil
+	}
+
+	parts := strings.Split(s, ",")
+	out := make(model.Line, len(parts))
+	for i, p := range parts {
+		pair := strings.SplitN(p, "/", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("station %d: %q is not next/subseq", i, p)
+		}
+		var err error
+		out[i].Next, err = strconv.Atoi(pair[0])
+		if err != nil {
+			return nil, fmt.Errorf("station %d: %w", i, err)
+		}
+		out[i].Subseq, err = strconv.Atoi(pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("station %d: %w", i, err)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,29 @@
+package recorder
+
+import (
+	"reflect"
+	"testing"
+
+	"go.lepak.sg/mrtracker-backend/model"
+)
+
+func TestArrivals(t *testing.T) {
+	l := model.Line{{Next: 0, Subseq: 4}, {Next: 12, Subseq: -1}, {Next: -1, Subseq: -1}}
+
+	s := EncodeArrivals(l)
+	if s != "0/4,12/-1,-1/-1" {
+		t.Errorf("unexpected encoding %q", s)
+	}
+
+	decoded, err := DecodeArrivals(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, l) {
+		t.Errorf("expected %v, got %v", l, decoded)
+	}
+
+	if _, err = DecodeArrivals("0/4,3"); err == nil {
+		t.Error("expected error for missing subseq")
+	}
+}
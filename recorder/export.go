@@ -0,0 +1,128 @@
+package recorder
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+
+	// rows per parquet row group, there's no point holding more than this in memory
+	parquetGroupRows = 10000
+)
+
+// ExportRecord is one row of an exported table: a line at one instant, with one value per column.
+// For positions the values are 1 where there is a train and 0 elsewhere, for arrivals they are
+// minutes, -1 if there is no train.
+type ExportRecord struct {
+	Time   time.Time
+	Line   string
+	Source string
+	Values []int
+}
+
+// Exporter writes a table with time, line and source columns followed by the value columns it
+// was made with. Close must be called to finish the file, it does not close the underlying writer.
+type Exporter interface {
+	Write(rec ExportRecord) error
+	Close() error
+}
+
+// NewExporter returns an Exporter for FormatCSV or FormatParquet
+func NewExporter(format string, w io.Writer, columns []string) (Exporter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVExporter(w, columns)
+	case FormatParquet:
+		return newParquetExporter(w, columns)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+type csvExporter struct {
+	w       *csv.Writer
+	columns int
+	buf     []string
+}
+
+func newCSVExporter(w io.Writer, columns []string) (*csvExporter, error) {
+	e := &csvExporter{
+		w:       csv.NewWriter(w),
+		columns: len(columns),
+	}
+	err := e.w.Write(append([]string{"time", "line", "source"}, columns...))
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *csvExporter) Write(rec ExportRecord) error {
+	if len(rec.Values) != e.columns {
+		return fmt.Errorf("export: %s at %v has %d values, expected %d", rec.Line, rec.Time, len(rec.Values), e.columns)
+	}
+
+	e.buf = append(e.buf[:0], rec.Time.Format(time.RFC3339), rec.Line, rec.Source)
+	for _, v := range rec.Values {
+		e.buf = append(e.buf, strconv.Itoa(v))
+	}
+	return e.w.Write(e.buf)
+}
+
+func (e *csvExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type parquetExporter struct {
+	w       *parquetWriter
+	columns int
+}
+
+func newParquetExporter(w io.Writer, columns []string) (*parquetExporter, error) {
+	cols := []parquetColumn{
+		{"time", parquetInt64, parquetTimestampMillis},
+		{"line", parquetByteArray, parquetUTF8},
+		{"source", parquetByteArray, parquetUTF8},
+	}
+	for _, c := range columns {
+		cols = append(cols, parquetColumn{c, parquetInt32, parquetNoConverted})
+	}
+
+	pw, err := newParquetWriter(w, cols)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetExporter{
+		w:       pw,
+		columns: len(columns),
+	}, nil
+}
+
+func (e *parquetExporter) Write(rec ExportRecord) error {
+	if len(rec.Values) != e.columns {
+		return fmt.Errorf("export: %s at %v has %d values, expected %d", rec.Line, rec.Time, len(rec.Values), e.columns)
+	}
+
+	row := make([]interface{}, 0, 3+len(rec.Values))
+	row = append(row, rec.Time.UnixNano()/int64(time.Millisecond), rec.Line, rec.Source)
+	for _, v := range rec.Values {
+		row = append(row, int32(v))
+	}
+	e.w.writeRow(row)
+
+	if e.w.rows >= parquetGroupRows {
+		return e.w.flush()
+	}
+	return nil
+}
+
+func (e *parquetExporter) Close() error {
+	return e.w.close()
+}
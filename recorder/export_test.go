@@ -0,0 +1,74 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestExporter(t *testing.T) {
+	rec := ExportRecord{
+		Time:   time.Date(2021, 9, 6, 8, 0, 0, 0, time.UTC),
+		Line:   "ew1",
+		Source: SourceLive,
+		Values: []int{1, 0, 0},
+	}
+	columns := []string{"EW1", "EW1-EW2", "EW2"}
+
+	var buf bytes.Buffer
+	e, err := NewExporter(FormatCSV, &buf, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.Write(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.Write(ExportRecord{Values: []int{1}})
+	if err == nil {
+		t.Error("expected a short record to fail")
+	}
+	err = e.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "time,line,source,EW1,EW1-EW2,EW2\n2021-09-06T08:00:00Z,ew1,live,1,0,0\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected csv %q", buf.String())
+	}
+
+	buf.Reset()
+	e, err = NewExporter(FormatParquet, &buf, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < parquetGroupRows+1; i++ {
+		err = e.Write(rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = e.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	if string(b[:4]) != parquetMagic || string(b[len(b)-4:]) != parquetMagic {
+		t.Fatal("missing parquet magic")
+	}
+	footer := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	if footer <= 0 || footer > len(b)-12 {
+		t.Errorf("bad footer length %d", footer)
+	}
+	if !bytes.Contains(b[len(b)-8-footer:], []byte("EW1-EW2")) {
+		t.Error("footer is missing the schema")
+	}
+
+	_, err = NewExporter("xlsx", &buf, columns)
+	if err == nil {
+		t.Error("expected unknown format to fail")
+	}
+}
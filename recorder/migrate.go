@@ -0,0 +1,158 @@
+package recorder
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations are numbered files in migrations/<dialect>, applied in order and at most once:
+//
+//	migrations/mysql/0002_add_source_arrivals_tries.sql
+//
+// Each file holds statements separated by semicolons at the end of a line. Lines starting with
+// -- are comments. Never edit a migration that has been released, add another one.
+//
+//go:embed migrations
+var migrationFS embed.FS
+
+const (
+	createSchemaVersion = "create table if not exists schema_version (" +
+		"version int not null primary key, applied_at bigint not null)"
+	querySchemaVersion  = "select coalesce(max(version), 0) from schema_version"
+	insertSchemaVersion = "insert into schema_version (version, applied_at) values (?, ?)"
+)
+
+type migration struct {
+	version    int
+	name       string
+	statements []string
+}
+
+func loadMigrations(dialect string) ([]migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := migrationFS.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []migration
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+
+		prefix := strings.SplitN(e.Name(), "_", 2)[0]
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: bad version: %w", e.Name(), err)
+		}
+
+		b, err := migrationFS.ReadFile(path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, migration{
+			version:    version,
+			name:       e.Name(),
+			statements: splitStatements(string(b)),
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].version < out[j].version })
+	for i := range out {
+		if out[i].version != i+1 {
+			return nil, fmt.Errorf("migration %s: expected version %d", out[i].name, i+1)
+		}
+	}
+
+	return out, nil
+}
+
+func splitStatements(src string) []string {
+	var out []string
+	var sb strings.Builder
+
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		sb.WriteString(line)
+		sb.WriteRune('\n')
+		if strings.HasSuffix(trimmed, ";") {
+			out = append(out, strings.TrimSuffix(strings.TrimSpace(sb.String()), ";"))
+			sb.Reset()
+		}
+	}
+	if s := strings.TrimSpace(sb.String()); s != "" {
+		out = append(out, s)
+	}
+
+	return out
+}
+
+// Migrate applies every migration newer than the schema version recorded in the database,
+// and returns how many were applied. Each migration is recorded in the same transaction
+// as its statements. MySQL commits DDL statements straight away though, so a migration that
+// failed after some of its statements may be run again: statements that fail because what they
+// create is already there are taken as done.
+func (s *sqlStore) Migrate(ctx context.Context) (int, error) {
+	migrations, err := loadMigrations(s.dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = s.db.ExecContext(ctx, createSchemaVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	var current int
+	err = s.db.QueryRowContext(ctx, querySchemaVersion).Scan(&current)
+	if err != nil {
+		return 0, err
+	}
+	if current > len(migrations) {
+		return 0, fmt.Errorf("schema version %d is newer than this build (%d)", current, len(migrations))
+	}
+
+	applied := 0
+	for _, m := range migrations[current:] {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return applied, err
+		}
+
+		for _, stmt := range m.statements {
+			_, err = tx.ExecContext(ctx, stmt)
+			if err != nil && s.applied != nil && s.applied(err) {
+				continue
+			}
+			if err != nil {
+				_ = tx.Rollback()
+				return applied, fmt.Errorf("migration %s: %w", m.name, err)
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, insertSchemaVersion, m.version, time.Now().UnixNano()/int64(time.Millisecond))
+		if err != nil {
+			_ = tx.Rollback()
+			return applied, fmt.Errorf("migration %s: %w", m.name, err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return applied, fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		applied++
+	}
+
+	return applied, nil
+}
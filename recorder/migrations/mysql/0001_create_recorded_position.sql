@@ -0,0 +1,11 @@
+-- Same as the table that used to be created by hand, so this does nothing on existing databases
+create table if not exists recorded_position (
+    id int primary key auto_increment,
+    day_of_week tinyint not null,
+    seconds_of_day int not null,
+    time timestamp not null,
+    name varchar(10) not null,
+    line_repr varchar(200) not null,
+    index day_second_name (day_of_week, seconds_of_day, name),
+    unique index time_name (time, name)
+);
@@ -0,0 +1,7 @@
+-- source: where the positions came from, eg live or recompute
+-- arrivals: next/subsequent arrival minutes at every station of the line, see EncodeArrivals
+-- tries: requests made to the upstream api for the whole tick
+alter table recorded_position
+    add column source varchar(20) not null default 'live',
+    add column arrivals varchar(1000) null,
+    add column tries int null;
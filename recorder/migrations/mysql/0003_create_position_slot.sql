@@ -0,0 +1,15 @@
+-- Downsampled recordings, see Store.Retain.
+-- counts: for every position of the line, how many samples had a train there, comma separated
+create table if not exists position_slot (
+    name varchar(10) not null,
+    day_of_week tinyint not null,
+    slot int not null,
+    samples int not null,
+    counts varchar(1000) not null,
+    primary key (name, day_of_week, slot)
+);
+
+create table if not exists recorder_state (
+    name varchar(50) not null primary key,
+    value bigint not null
+);
@@ -0,0 +1,12 @@
+-- The time column holds unix milliseconds. The driver would store time.Time as text,
+-- which doesn't compare correctly across time zones and fractional seconds.
+create table if not exists recorded_position (
+    id integer primary key autoincrement,
+    day_of_week integer not null,
+    seconds_of_day integer not null,
+    time integer not null,
+    name text not null,
+    line_repr text not null
+);
+create index if not exists day_second_name on recorded_position (day_of_week, seconds_of_day, name);
+create unique index if not exists time_name on recorded_position (time, name);
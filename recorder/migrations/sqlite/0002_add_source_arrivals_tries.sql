@@ -0,0 +1,4 @@
+-- See the mysql migration of the same name
+alter table recorded_position add column source text not null default 'live';
+alter table recorded_position add column arrivals text null;
+alter table recorded_position add column tries integer null;
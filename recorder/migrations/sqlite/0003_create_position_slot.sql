@@ -0,0 +1,14 @@
+-- See the mysql migration of the same name
+create table if not exists position_slot (
+    name text not null,
+    day_of_week integer not null,
+    slot integer not null,
+    samples integer not null,
+    counts text not null,
+    primary key (name, day_of_week, slot)
+);
+
+create table if not exists recorder_state (
+    name text not null primary key,
+    value integer not null
+);
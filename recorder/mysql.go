@@ -0,0 +1,76 @@
+package recorder
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL keeps recordings in a MySQL database. The database and user are set up by
+// cmd/recorder/create.sql, tables are created by Migrate.
+type MySQL struct {
+	sqlStore
+}
+
+var _ Store = (*MySQL)(nil)
+
+func NewMySQL(dsn string) (*MySQL, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	// so that the time column scans into time.Time
+	cfg.ParseTime = true
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+	err = db.Ping()
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+	db.SetConnMaxLifetime(1 * time.Hour)
+	db.SetConnMaxIdleTime(1 * time.Hour)
+
+	return &MySQL{
+		sqlStore{
+			db: db,
+			encodeTime: func(t time.Time) interface{} {
+				return t
+			},
+			// not insert ignore, which also lets through truncated and invalid values
+			insertIgnore:       "insert",
+			insertIgnoreSuffix: " on duplicate key update id=id",
+			dialect:            BackendMySQL,
+			applied:            mysqlApplied,
+		},
+	}, nil
+}
+
+// MySQL error numbers for creating what is already there
+const (
+	errTableExists     = 1050
+	errDuplicateColumn = 1060
+	errDuplicateKey    = 1061
+)
+
+// mysqlApplied recognises a DDL statement that was committed before its migration was
+// recorded, as MySQL commits DDL straight away
+func mysqlApplied(err error) bool {
+	var me *mysql.MySQLError
+	if !errors.As(err, &me) {
+		return false
+	}
+	switch me.Number {
+	case errTableExists, errDuplicateColumn, errDuplicateKey:
+		return true
+	}
+	return false
+}
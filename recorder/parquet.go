@@ -0,0 +1,317 @@
+package recorder
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// A minimal Parquet writer, just enough for exports: flat required columns of int32, int64 and
+// strings, plain encoding, no compression, one data page per column per row group.
+// See https://github.com/apache/parquet-format for the format and its thrift definitions.
+
+const parquetMagic = "PAR1"
+
+// parquet physical types
+const (
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetByteArray = 6
+)
+
+// parquet converted types, -1 for none
+const (
+	parquetNoConverted     = -1
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+)
+
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32
+}
+
+type parquetColumnChunk struct {
+	offset           int64
+	size             int64
+	numValues        int64
+	column           parquetColumn
+	dataPageOffset   int64
+	uncompressedSize int64
+}
+
+type parquetRowGroup struct {
+	columns []parquetColumnChunk
+	size    int64
+	rows    int64
+}
+
+type parquetWriter struct {
+	w       *bufio.Writer
+	offset  int64
+	columns []parquetColumn
+
+	// values of the current row group, one buffer per column, already plain encoded
+	values [][]byte
+	rows   int64
+
+	groups    []parquetRowGroup
+	totalRows int64
+}
+
+func newParquetWriter(w io.Writer, columns []parquetColumn) (*parquetWriter, error) {
+	pw := &parquetWriter{
+		w:       bufio.NewWriter(w),
+		columns: columns,
+		values:  make([][]byte, len(columns)),
+	}
+	return pw, pw.write([]byte(parquetMagic))
+}
+
+func (pw *parquetWriter) write(b []byte) error {
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	return err
+}
+
+// writeRow appends a row to the current row group. Values must be int32, int64 or string,
+// matching the column types.
+func (pw *parquetWriter) writeRow(row []interface{}) {
+	for i, v := range row {
+		switch v := v.(type) {
+		case int32:
+			pw.values[i] = appendUint32(pw.values[i], uint32(v))
+		case int64:
+			pw.values[i] = appendUint32(pw.values[i], uint32(v))
+			pw.values[i] = appendUint32(pw.values[i], uint32(uint64(v)>>32))
+		case string:
+			pw.values[i] = appendUint32(pw.values[i], uint32(len(v)))
+			pw.values[i] = append(pw.values[i], v...)
+		default:
+			panic("parquet: unsupported value type")
+		}
+	}
+	pw.rows++
+}
+
+// flush writes the current row group
+func (pw *parquetWriter) flush() error {
+	if pw.rows == 0 {
+		return nil
+	}
+
+	group := parquetRowGroup{rows: pw.rows}
+	for i, col := range pw.columns {
+		var th thriftWriter
+		th.begin()
+		th.fieldI32(1, 0) // type: DATA_PAGE
+		th.fieldI32(2, int32(len(pw.values[i])))
+		th.fieldI32(3, int32(len(pw.values[i])))
+		th.fieldStruct(5)
+		th.fieldI32(1, int32(pw.rows))
+		th.fieldI32(2, 0) // encoding: PLAIN
+		th.fieldI32(3, 3) // definition levels: RLE, though there aren't any
+		th.fieldI32(4, 3) // repetition levels: RLE
+		th.stop()
+		th.stop()
+
+		chunk := parquetColumnChunk{
+			offset:         pw.offset,
+			numValues:      pw.rows,
+			column:         col,
+			dataPageOffset: pw.offset,
+		}
+		err := pw.write(th.buf)
+		if err != nil {
+			return err
+		}
+		err = pw.write(pw.values[i])
+		if err != nil {
+			return err
+		}
+		chunk.size = pw.offset - chunk.offset
+		chunk.uncompressedSize = chunk.size
+
+		group.columns = append(group.columns, chunk)
+		group.size += chunk.size
+		pw.values[i] = pw.values[i][:0]
+	}
+
+	pw.groups = append(pw.groups, group)
+	pw.totalRows += pw.rows
+	pw.rows = 0
+	return nil
+}
+
+// close flushes the last row group and writes the footer
+func (pw *parquetWriter) close() error {
+	err := pw.flush()
+	if err != nil {
+		return err
+	}
+
+	var th thriftWriter
+	th.begin()
+	th.fieldI32(1, 1) // version
+
+	th.fieldList(2, thriftStruct, len(pw.columns)+1)
+	th.begin()
+	th.fieldString(4, "schema")
+	th.fieldI32(5, int32(len(pw.columns)))
+	th.stop()
+	for _, col := range pw.columns {
+		th.begin()
+		th.fieldI32(1, col.typ)
+		th.fieldI32(3, 0) // repetition: REQUIRED
+		th.fieldString(4, col.name)
+		if col.converted != parquetNoConverted {
+			th.fieldI32(6, col.converted)
+		}
+		th.stop()
+	}
+
+	th.fieldI64(3, pw.totalRows)
+
+	th.fieldList(4, thriftStruct, len(pw.groups))
+	for _, g := range pw.groups {
+		th.begin()
+		th.fieldList(1, thriftStruct, len(g.columns))
+		for _, c := range g.columns {
+			th.begin()
+			th.fieldI64(2, c.offset)
+			th.fieldStruct(3)
+			th.fieldI32(1, c.column.typ)
+			th.fieldList(2, thriftI32, 2)
+			th.i32(0) // PLAIN
+			th.i32(3) // RLE
+			th.fieldList(3, thriftBinary, 1)
+			th.binary(c.column.name)
+			th.fieldI32(4, 0) // codec: UNCOMPRESSED
+			th.fieldI64(5, c.numValues)
+			th.fieldI64(6, c.uncompressedSize)
+			th.fieldI64(7, c.size)
+			th.fieldI64(9, c.dataPageOffset)
+			th.stop()
+			th.stop()
+		}
+		th.fieldI64(2, g.size)
+		th.fieldI64(3, g.rows)
+		th.stop()
+	}
+
+	th.fieldString(6, "mrtracker-backend")
+	th.stop()
+
+	err = pw.write(th.buf)
+	if err != nil {
+		return err
+	}
+	err = pw.write(appendUint32(nil, uint32(len(th.buf))))
+	if err != nil {
+		return err
+	}
+	err = pw.write([]byte(parquetMagic))
+	if err != nil {
+		return err
+	}
+	return pw.w.Flush()
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var tmp [4]byte
+	binary.LittleEndian.PutUint32(tmp[:], v)
+	return append(b, tmp[:]...)
+}
+
+// thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs in the thrift compact protocol. Structs are started with begin,
+// or fieldStruct when nested, and ended with stop. Fields must be written in increasing id order.
+type thriftWriter struct {
+	buf []byte
+	// last field id of each struct being written, innermost last
+	last []int16
+}
+
+// begin starts a struct at the top level or as a list element
+func (t *thriftWriter) begin() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) fieldI32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.i32(v)
+}
+
+func (t *thriftWriter) fieldI64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) fieldString(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.binary(s)
+}
+
+// fieldStruct starts a nested struct
+func (t *thriftWriter) fieldStruct(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.begin()
+}
+
+// fieldList starts a list of n elements, which follow straight away
+func (t *thriftWriter) fieldList(id int16, elem byte, n int) {
+	t.fieldHeader(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|elem)
+	} else {
+		t.buf = append(t.buf, 0xf0|elem)
+		t.varint(uint64(n))
+	}
+}
+
+// stop ends a struct
+func (t *thriftWriter) stop() {
+	t.buf = append(t.buf, 0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) i32(v int32) {
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) binary(s string) {
+	t.varint(uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	for v >= 0x80 {
+		t.buf = append(t.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	t.buf = append(t.buf, byte(v))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
@@ -0,0 +1,278 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// A reader for what parquetWriter writes, decoding the footer and pages the way any Parquet
+// reader would, so the test doesn't only check the writer against itself.
+
+// thriftReader decodes the thrift compact protocol into maps of field id to value. Integers are
+// int64, binaries []byte, lists []interface{} and structs map[int16]interface{}.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+var errThriftShort = errors.New("thrift: unexpected end of data")
+
+func (r *thriftReader) byte() (byte, error) {
+	if r.pos >= len(r.b) {
+		return 0, errThriftShort
+	}
+	r.pos++
+	return r.b[r.pos-1], nil
+}
+
+func (r *thriftReader) varint() (uint64, error) {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		c, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(c&0x7f) << shift
+		if c < 0x80 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("thrift: varint too long")
+}
+
+func (r *thriftReader) zigzag() (int64, error) {
+	v, err := r.varint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (r *thriftReader) value(typ byte) (interface{}, error) {
+	switch typ {
+	case 1, 2: // bool, in a list or a field header
+		return typ == 1, nil
+	case 3:
+		c, err := r.byte()
+		return int64(int8(c)), err
+	case 4, thriftI32, thriftI64:
+		return r.zigzag()
+	case 7:
+		if r.pos+8 > len(r.b) {
+			return nil, errThriftShort
+		}
+		r.pos += 8
+		return nil, nil
+	case thriftBinary:
+		n, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		if r.pos+int(n) > len(r.b) {
+			return nil, errThriftShort
+		}
+		r.pos += int(n)
+		return r.b[r.pos-int(n) : r.pos], nil
+	case thriftList, 10:
+		h, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		n := uint64(h >> 4)
+		if n == 15 {
+			n, err = r.varint()
+			if err != nil {
+				return nil, err
+			}
+		}
+		out := make([]interface{}, n)
+		for i := range out {
+			out[i], err = r.value(h & 0x0f)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case thriftStruct:
+		return r.structure()
+	}
+	return nil, fmt.Errorf("thrift: unsupported type %d", typ)
+}
+
+func (r *thriftReader) structure() (map[int16]interface{}, error) {
+	out := make(map[int16]interface{})
+	var last int16
+	for {
+		h, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if h == 0 {
+			return out, nil
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			v, err := r.zigzag()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		out[id], err = r.value(h & 0x0f)
+		if err != nil {
+			return nil, err
+		}
+		last = id
+	}
+}
+
+type parquetFile struct {
+	// name, physical type and converted type of each column, -1 for none
+	columns    []parquetColumn
+	rows       [][]interface{}
+	numRows    int64
+	groupCount int
+}
+
+func readParquet(b []byte) (*parquetFile, error) {
+	if len(b) < 12 || string(b[:4]) != parquetMagic || string(b[len(b)-4:]) != parquetMagic {
+		return nil, errors.New("missing magic")
+	}
+	n := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	if n > len(b)-12 {
+		return nil, errors.New("bad footer length")
+	}
+	r := &thriftReader{b: b[len(b)-8-n : len(b)-8]}
+	meta, err := r.structure()
+	if err != nil {
+		return nil, fmt.Errorf("footer: %w", err)
+	}
+
+	out := &parquetFile{numRows: meta[3].(int64)}
+	schema := meta[2].([]interface{})
+	root := schema[0].(map[int16]interface{})
+	if int(root[5].(int64)) != len(schema)-1 {
+		return nil, errors.New("schema root has the wrong number of children")
+	}
+	for _, e := range schema[1:] {
+		el := e.(map[int16]interface{})
+		if el[3].(int64) != 0 {
+			return nil, fmt.Errorf("column %s is not required", el[4])
+		}
+		col := parquetColumn{name: string(el[4].([]byte)), typ: int32(el[1].(int64)), converted: parquetNoConverted}
+		if c, ok := el[6]; ok {
+			col.converted = int32(c.(int64))
+		}
+		out.columns = append(out.columns, col)
+	}
+
+	for _, g := range meta[4].([]interface{}) {
+		group := g.(map[int16]interface{})
+		numRows := int(group[3].(int64))
+		rows := make([][]interface{}, numRows)
+		for i := range rows {
+			rows[i] = make([]interface{}, len(out.columns))
+		}
+
+		chunks := group[1].([]interface{})
+		if len(chunks) != len(out.columns) {
+			return nil, errors.New("row group has the wrong number of columns")
+		}
+		for i, c := range chunks {
+			cm := c.(map[int16]interface{})[3].(map[int16]interface{})
+			if cm[4].(int64) != 0 {
+				return nil, errors.New("compressed column")
+			}
+			if int(cm[5].(int64)) != numRows {
+				return nil, errors.New("column chunk has the wrong number of values")
+			}
+
+			page := &thriftReader{b: b[cm[9].(int64):]}
+			header, err := page.structure()
+			if err != nil {
+				return nil, fmt.Errorf("page header: %w", err)
+			}
+			dataHeader := header[5].(map[int16]interface{})
+			if header[1].(int64) != 0 || dataHeader[2].(int64) != 0 || int(dataHeader[1].(int64)) != numRows {
+				return nil, errors.New("not a plain data page of the row group")
+			}
+			values := page.b[page.pos : page.pos+int(header[3].(int64))]
+
+			for j := range rows {
+				switch out.columns[i].typ {
+				case parquetInt32:
+					rows[j][i] = int32(binary.LittleEndian.Uint32(values))
+					values = values[4:]
+				case parquetInt64:
+					rows[j][i] = int64(binary.LittleEndian.Uint64(values))
+					values = values[8:]
+				case parquetByteArray:
+					n := int(binary.LittleEndian.Uint32(values))
+					rows[j][i] = string(values[4 : 4+n])
+					values = values[4+n:]
+				}
+			}
+			if len(values) != 0 {
+				return nil, fmt.Errorf("column %s: %d bytes left over", out.columns[i].name, len(values))
+			}
+		}
+		out.rows = append(out.rows, rows...)
+		out.groupCount++
+	}
+
+	return out, nil
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	e, err := NewExporter(FormatParquet, &buf, []string{"EW1", "EW1-EW2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2021, 9, 6, 8, 0, 0, 0, time.UTC)
+	var want [][]interface{}
+	for i := 0; i < parquetGroupRows+3; i++ {
+		rec := ExportRecord{
+			Time:   start.Add(time.Duration(i) * 30 * time.Second),
+			Line:   "ew1",
+			Source: []string{SourceLive, SourceRecompute}[i%2],
+			Values: []int{i % 2, -i},
+		}
+		err = e.Write(rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, []interface{}{
+			rec.Time.UnixNano() / int64(time.Millisecond), rec.Line, rec.Source, int32(i % 2), int32(-i),
+		})
+	}
+	err = e.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := readParquet(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns := []parquetColumn{
+		{"time", parquetInt64, parquetTimestampMillis},
+		{"line", parquetByteArray, parquetUTF8},
+		{"source", parquetByteArray, parquetUTF8},
+		{"EW1", parquetInt32, parquetNoConverted},
+		{"EW1-EW2", parquetInt32, parquetNoConverted},
+	}
+	if !reflect.DeepEqual(f.columns, columns) {
+		t.Errorf("got columns %+v", f.columns)
+	}
+	if f.groupCount != 2 || f.numRows != int64(len(want)) {
+		t.Errorf("got %d row groups and %d rows", f.groupCount, f.numRows)
+	}
+	if !reflect.DeepEqual(f.rows, want) {
+		t.Errorf("rows differ, first got %v, want %v", f.rows[:2], want[:2])
+	}
+}
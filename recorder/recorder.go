@@ -0,0 +1,101 @@
+package recorder
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+const (
+	BackendMySQL  = "mysql"
+	BackendSQLite = "sqlite"
+)
+
+const (
+	SourceLive      = "live"
+	SourceRecompute = "recompute"
+)
+
+// Row is one line's position at one point in time, as written by cmd/recorder
+type Row struct {
+	Time   time.Time
+	Name   string
+	Repr   string
+	Source string
+	// Empty if not recorded, see EncodeArrivals
+	Arrivals string
+}
+
+// Snapshot is the position of every line at one point in time
+type Snapshot struct {
+	Time time.Time `json:"time"`
+	// line name -> Position.ToString()
+	Lines map[string]string `json:"lines"`
+	// line name -> EncodeArrivals, optional
+	Arrivals map[string]string `json:"arrivals,omitempty"`
+	// Defaults to SourceLive
+	Source string `json:"source,omitempty"`
+	// Requests made upstream for the whole snapshot, 0 if unknown
+	Tries int `json:"tries,omitempty"`
+}
+
+// Store is where recorded positions are kept
+type Store interface {
+	// Save writes one row per line in the snapshot
+	Save(ctx context.Context, s Snapshot) error
+	// Replace is like Save, but overwrites rows that already exist for the same time and line
+	Replace(ctx context.Context, s Snapshot) error
+	// QueryRange returns at most limit rows for the line recorded from (inclusive) to (exclusive),
+	// ordered by time.
+	QueryRange(ctx context.Context, name string, from, to time.Time, limit int) ([]Row, error)
+	// QuerySlot returns every row for the line recorded on dayOfWeek (0 is Sunday) between
+	// fromSecond and toSecond of the day inclusive, on any date, ordered by time.
+	QuerySlot(ctx context.Context, name string, dayOfWeek, fromSecond, toSecond int) ([]Row, error)
+	// Nearest returns the row of a line closest in time to t, false if there are none
+	Nearest(ctx context.Context, name string, t time.Time) (Row, bool, error)
+	// Typical returns the occupancy of a line in the slot of the week containing the given time,
+	// over every recorded week
+	Typical(ctx context.Context, name string, dayOfWeek, secondsOfDay int) (Aggregate, error)
+	// Retain downsamples and deletes old rows, see RetentionConfig
+	Retain(ctx context.Context, c RetentionConfig) (RetentionStats, error)
+	// Migrate brings the schema up to date and returns the number of migrations applied
+	Migrate(ctx context.Context) (int, error)
+	Close() error
+}
+
+// Open connects to a store. For mysql, dsn is a go-sql-driver DSN, for sqlite it is a file path.
+func Open(backend, dsn string) (Store, error) {
+	switch backend {
+	case BackendMySQL:
+		return NewMySQL(dsn)
+	case BackendSQLite:
+		return NewSQLite(dsn)
+	default:
+		return nil, fmt.Errorf("unrecognized store backend: %q", backend)
+	}
+}
+
+// NewSnapshot infers the position of every line from the raw results, and keeps the arrivals
+// they were inferred from.
+func NewSnapshot(t time.Time, results map[string]smrt.Result) Snapshot {
+	out := Snapshot{
+		Time:     t,
+		Lines:    make(map[string]string),
+		Arrivals: make(map[string]string),
+		Source:   SourceLive,
+	}
+	for _, l := range data.GetLines() {
+		ml := smrt.ToModel(results, l.Line)
+		out.Lines[l.Name] = ml.ToPositionFor(l.Line).ToString()
+		out.Arrivals[l.Name] = EncodeArrivals(ml)
+	}
+	return out
+}
+
+// slotOf returns the weekday (0 is Sunday) and second of the day of t in its own location
+func slotOf(t time.Time) (int, int) {
+	return int(t.Weekday()), t.Hour()*3600 + t.Minute()*60 + t.Second()
+}
@@ -0,0 +1,313 @@
+package recorder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/model"
+)
+
+const (
+	defaultKeepRaw = 30 * 24 * time.Hour
+	defaultHorizon = 365 * 24 * time.Hour
+	defaultSlot    = 5 * time.Minute
+
+	stateAggregatedUntil = "aggregated_until" // unix ms, every raw row before this is in position_slot
+	stateSlotSeconds     = "slot_seconds"
+
+	queryMinTime   = "select min(time) from recorded_position"
+	queryState     = "select value from recorder_state where name = ?"
+	replaceState   = "replace into recorder_state (name, value) values (?, ?)"
+	queryDayRows   = "select day_of_week, seconds_of_day, name, line_repr from recorded_position where time >= ? and time < ?"
+	queryAggregate = "select samples, counts from position_slot where name = ? and day_of_week = ? and slot = ?"
+	replaceAgg     = "replace into position_slot (name, day_of_week, slot, samples, counts) values (?, ?, ?, ?, ?)"
+	deleteDayRows  = "delete from recorded_position where time >= ? and time < ?"
+)
+
+type RetentionConfig struct {
+	// Raw rows younger than this are left alone. Older ones are folded into aggregates.
+	KeepRaw time.Duration
+	// Raw rows older than this are deleted, but only once they have been aggregated
+	Horizon time.Duration
+	// Width of an aggregate slot. It can't be changed once there are aggregates.
+	Slot time.Duration
+	// Defaults to time.Now()
+	Now time.Time
+}
+
+type RetentionStats struct {
+	DaysAggregated int
+	RowsAggregated int
+	RowsDeleted    int64
+}
+
+// Aggregate is how often each position of a line was occupied in one slot of the week,
+// over every recorded date.
+type Aggregate struct {
+	Name      string
+	DayOfWeek int
+	// Seconds of the day the slot starts at
+	Slot    int
+	Samples int
+	// Per position, how many samples had a train there
+	Counts []int
+}
+
+// Occupancy is the probability of a train being at each position
+func (a Aggregate) Occupancy() model.Occupancy {
+	out := make(model.Occupancy, len(a.Counts))
+	if a.Samples == 0 {
+		return out
+	}
+	for i := range a.Counts {
+		out[i] = float64(a.Counts[i]) / float64(a.Samples)
+	}
+	return out
+}
+
+func (c *RetentionConfig) validate() error {
+	if c.KeepRaw == 0 {
+		c.KeepRaw = defaultKeepRaw
+	}
+	if c.Horizon == 0 {
+		c.Horizon = defaultHorizon
+	}
+	if c.Slot == 0 {
+		c.Slot = defaultSlot
+	}
+	if c.Now.IsZero() {
+		c.Now = time.Now()
+	}
+
+	if c.KeepRaw < 24*time.Hour {
+		return errors.New("retention: must keep at least a day of raw rows")
+	}
+	if c.Horizon < c.KeepRaw {
+		return errors.New("retention: horizon is shorter than raw retention")
+	}
+	if c.Slot < time.Second || (24*time.Hour)%c.Slot != 0 {
+		return errors.New("retention: slot must divide a day evenly")
+	}
+	return nil
+}
+
+// Retain folds raw rows older than KeepRaw into per-slot aggregates, then deletes raw rows older
+// than Horizon. Work is done one local day at a time, each in its own transaction, and progress
+// is recorded, so it is safe to interrupt and run again.
+func (s *sqlStore) Retain(ctx context.Context, c RetentionConfig) (RetentionStats, error) {
+	var stats RetentionStats
+	err := c.validate()
+	if err != nil {
+		return stats, err
+	}
+	slotSeconds := int(c.Slot / time.Second)
+
+	stored, ok, err := s.state(ctx, stateSlotSeconds)
+	if err != nil {
+		return stats, err
+	}
+	if ok && int(stored) != slotSeconds {
+		return stats, fmt.Errorf("retention: aggregates use %ds slots, not %ds", stored, slotSeconds)
+	}
+	_, err = s.db.ExecContext(ctx, replaceState, stateSlotSeconds, slotSeconds)
+	if err != nil {
+		return stats, err
+	}
+
+	var earliest time.Time
+	err = s.db.QueryRowContext(ctx, queryMinTime).Scan(timeScanner{&earliest})
+	if err != nil {
+		return stats, err
+	}
+	if earliest.IsZero() {
+		// nothing recorded
+		return stats, nil
+	}
+	earliest = earliest.In(c.Now.Location())
+
+	untilMs, ok, err := s.state(ctx, stateAggregatedUntil)
+	if err != nil {
+		return stats, err
+	}
+	until := startOfDay(earliest)
+	if ok {
+		until = time.Unix(0, untilMs*int64(time.Millisecond)).In(c.Now.Location())
+	}
+
+	aggregateBefore := startOfDay(c.Now.Add(-c.KeepRaw))
+	for day := until; day.Before(aggregateBefore); day = day.AddDate(0, 0, 1) {
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
+		n, err := s.aggregateDay(ctx, day, slotSeconds)
+		if err != nil {
+			return stats, fmt.Errorf("retention: aggregating %s: %w", day.Format("2006-01-02"), err)
+		}
+		stats.DaysAggregated++
+		stats.RowsAggregated += n
+		until = day.AddDate(0, 0, 1)
+	}
+
+	deleteBefore := startOfDay(c.Now.Add(-c.Horizon))
+	if until.Before(deleteBefore) {
+		// never delete what hasn't been aggregated
+		deleteBefore = until
+	}
+	for day := startOfDay(earliest); day.Before(deleteBefore); day = day.AddDate(0, 0, 1) {
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
+		res, err := s.db.ExecContext(ctx, deleteDayRows, s.encodeTime(day), s.encodeTime(day.AddDate(0, 0, 1)))
+		if err != nil {
+			return stats, fmt.Errorf("retention: deleting %s: %w", day.Format("2006-01-02"), err)
+		}
+		n, _ := res.RowsAffected()
+		stats.RowsDeleted += n
+	}
+
+	return stats, nil
+}
+
+// aggregateDay adds every raw row on one day to the aggregates and moves the progress marker
+// past it, all in one transaction.
+func (s *sqlStore) aggregateDay(ctx context.Context, day time.Time, slotSeconds int) (int, error) {
+	next := day.AddDate(0, 0, 1)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback() // no-op after commit
+	}()
+
+	rows, err := tx.QueryContext(ctx, queryDayRows, s.encodeTime(day), s.encodeTime(next))
+	if err != nil {
+		return 0, err
+	}
+
+	type aggKey struct {
+		name      string
+		dayOfWeek int
+		slot      int
+	}
+	aggs := make(map[aggKey]*Aggregate)
+	count := 0
+
+	for rows.Next() {
+		var dayOfWeek, secondsOfDay int
+		var name, repr string
+		err = rows.Scan(&dayOfWeek, &secondsOfDay, &name, &repr)
+		if err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+
+		p, err := model.NewPositionFromString(repr)
+		if err != nil {
+			// skip garbage rather than get stuck on it forever
+			continue
+		}
+
+		k := aggKey{name, dayOfWeek, secondsOfDay - secondsOfDay%slotSeconds}
+		agg, ok := aggs[k]
+		if !ok {
+			agg = &Aggregate{Name: k.name, DayOfWeek: k.dayOfWeek, Slot: k.slot, Counts: make([]int, len(p))}
+			aggs[k] = agg
+		}
+		if len(agg.Counts) != len(p) {
+			// the line changed during the day, only keep the newer shape
+			agg.Samples = 0
+			agg.Counts = make([]int, len(p))
+		}
+		agg.add(p)
+		count++
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, agg := range aggs {
+		var samples int
+		var counts string
+		err = tx.QueryRowContext(ctx, queryAggregate, agg.Name, agg.DayOfWeek, agg.Slot).Scan(&samples, &counts)
+		if err == nil {
+			prev, err := decodeCounts(counts)
+			if err == nil && len(prev) == len(agg.Counts) {
+				agg.Samples += samples
+				for i := range prev {
+					agg.Counts[i] += prev[i]
+				}
+			}
+		} else if err != sql.ErrNoRows {
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, replaceAgg, agg.Name, agg.DayOfWeek, agg.Slot, agg.Samples, encodeCounts(agg.Counts))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, replaceState, stateAggregatedUntil, next.UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
+
+func (s *sqlStore) state(ctx context.Context, name string) (int64, bool, error) {
+	var v int64
+	err := s.db.QueryRowContext(ctx, queryState, name).Scan(&v)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+	return v, true, nil
+}
+
+func (a *Aggregate) add(p model.Position) {
+	a.Samples++
+	for i := range p {
+		if p[i] {
+			a.Counts[i]++
+		}
+	}
+}
+
+func encodeCounts(counts []int) string {
+	parts := make([]string, len(counts))
+	for i := range counts {
+		parts[i] = strconv.Itoa(counts[i])
+	}
+	return strings.Join(parts, ",")
+}
+
+func decodeCounts(s string) ([]int, error) {
+	if s == "" {
+		return []int{}, nil
+	}
+	parts := strings.Split(s, ",")
+	out := make([]int, len(parts))
+	for i := range parts {
+		var err error
+		out[i], err = strconv.Atoi(parts[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,102 @@
+package recorder
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSQLite_Retain(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "recorder.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	_, err = s.Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 8:00 to 8:09:30 on five consecutive mondays
+	firstDay := time.Date(2021, 8, 2, 8, 0, 0, 0, time.Local)
+	for week := 0; week < 5; week++ {
+		for i := 0; i < 20; i++ {
+			repr := "*__"
+			if i%2 == 1 {
+				repr = "_*_"
+			}
+			err = s.Save(ctx, Snapshot{
+				Time:  firstDay.AddDate(0, 0, 7*week).Add(time.Duration(i) * 30 * time.Second),
+				Lines: map[string]string{"ew1": repr},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	c := RetentionConfig{
+		KeepRaw: 10 * 24 * time.Hour,
+		Horizon: 20 * 24 * time.Hour,
+		Slot:    5 * time.Minute,
+		// the first four mondays are aggregated, the first three are past the horizon
+		Now: firstDay.AddDate(0, 0, 35),
+	}
+
+	stats, err := s.Retain(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.RowsAggregated != 80 || stats.RowsDeleted != 60 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// running again is a no-op
+	stats, err = s.Retain(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.RowsAggregated != 0 || stats.RowsDeleted != 0 {
+		t.Errorf("expected nothing to do, got %+v", stats)
+	}
+
+	var samples int
+	var counts string
+	err = s.db.QueryRow(queryAggregate, "ew1", 1, 8*3600).Scan(&samples, &counts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, _ := decodeCounts(counts)
+	if samples != 40 || !reflect.DeepEqual(decoded, []int{20, 20, 0}) {
+		t.Errorf("unexpected aggregate %d %q", samples, counts)
+	}
+
+	rows, err := s.QueryRange(ctx, "ew1", firstDay, c.Now, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 40 {
+		t.Errorf("expected the last two mondays of raw rows, got %d", len(rows))
+	}
+
+	// the aggregates plus the last monday, which is still raw
+	agg, err := s.Typical(ctx, "ew1", 1, 8*3600+120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agg.Slot != 8*3600 || agg.Samples != 50 || !reflect.DeepEqual(agg.Counts, []int{25, 25, 0}) {
+		t.Errorf("unexpected typical %+v", agg)
+	}
+	if occ := agg.Occupancy(); occ.ToPosition(0.5).ToString() != "**_" {
+		t.Errorf("unexpected typical occupancy %v", occ)
+	}
+
+	c.Slot = time.Minute
+	_, err = s.Retain(ctx, c)
+	if err == nil {
+		t.Error("expected changing the slot width to fail")
+	}
+}
@@ -0,0 +1,167 @@
+package recorder
+
+import (
+	"sort"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/model"
+)
+
+const (
+	// arrivals further apart than this don't follow a train, so tracking starts over
+	maxArrivalsGap = 2 * time.Minute
+	// longer runs or dwells are trains that were missed, or held up
+	maxRun   = 15 * time.Minute
+	maxDwell = 10 * time.Minute
+)
+
+// RunTimeLearner estimates run and dwell times between stations from recorded arrivals. At
+// every station it watches for the next train's minutes to reach 0, when a train arrives, and
+// to leave 0, when it departs. A train departing one station and arriving at the next is a run,
+// and the time between it arriving and departing is a dwell.
+type RunTimeLearner struct {
+	lines    map[string]data.Line
+	tracking map[string]*lineTracking
+	// by "from-to" station codes, in the order the lines reach them
+	segments map[string]*segmentSamples
+	order    []string
+}
+
+// lineTracking is what a line's stations showed last time, and when the train there
+// arrived or the last one left. Times are zero where they aren't known.
+type lineTracking struct {
+	last     time.Time
+	prev     model.Line
+	arrived  []time.Time
+	departed []time.Time
+}
+
+type segmentSamples struct {
+	from, to    string
+	runs, dwell []time.Duration
+}
+
+// NewRunTimeLearner learns the run times of the given lines
+func NewRunTimeLearner(lines []data.LineNameDataPair) *RunTimeLearner {
+	r := &RunTimeLearner{
+		lines:    make(map[string]data.Line),
+		tracking: make(map[string]*lineTracking),
+		segments: make(map[string]*segmentSamples),
+	}
+	for _, l := range lines {
+		r.lines[l.Name] = l.Line
+		for i := 1; i < len(l.Line); i++ {
+			key := l.Line[i-1].Code + "-" + l.Line[i].Code
+			if _, ok := r.segments[key]; ok {
+				continue
+			}
+			r.segments[key] = &segmentSamples{from: l.Line[i-1].Code, to: l.Line[i].Code}
+			r.order = append(r.order, key)
+		}
+	}
+	return r
+}
+
+// Add takes the arrivals at a line's stations at time t. Each line's arrivals must be added in
+// time order. Unknown lines, and arrivals recorded before the line changed, are ignored.
+func (r *RunTimeLearner) Add(t time.Time, name string, arrivals model.Line) {
+	l, ok := r.lines[name]
+	if !ok || len(arrivals) != len(l) {
+		return
+	}
+
+	lt := r.tracking[name]
+	if lt == nil || !t.After(lt.last) || t.Sub(lt.last) > maxArrivalsGap {
+		r.tracking[name] = &lineTracking{
+			last:     t,
+			prev:     arrivals,
+			arrived:  make([]time.Time, len(l)),
+			departed: make([]time.Time, len(l)),
+		}
+		return
+	}
+
+	for i := range arrivals {
+		was, now := lt.prev[i].Next, arrivals[i].Next
+		switch {
+		case now == -1:
+			lt.arrived[i], lt.departed[i] = time.Time{}, time.Time{}
+
+		case was > 0 && now == 0:
+			if i > 0 && !lt.departed[i-1].IsZero() {
+				if run := t.Sub(lt.departed[i-1]); run <= maxRun {
+					s := r.segment(l, i-1)
+					s.runs = append(s.runs, run)
+				}
+				lt.departed[i-1] = time.Time{}
+			}
+			lt.arrived[i] = t
+
+		case was == 0 && now > 0:
+			if !lt.arrived[i].IsZero() && i < len(l)-1 {
+				if dwell := t.Sub(lt.arrived[i]); dwell <= maxDwell {
+					s := r.segment(l, i)
+					s.dwell = append(s.dwell, dwell)
+				}
+			}
+			lt.arrived[i] = time.Time{}
+			lt.departed[i] = t
+		}
+	}
+
+	lt.prev = arrivals
+	lt.last = t
+}
+
+// segment returns the samples of the track from the station i of the line to the next one
+func (r *RunTimeLearner) segment(l data.Line, i int) *segmentSamples {
+	return r.segments[l[i].Code+"-"+l[i+1].Code]
+}
+
+// Samples returns the number of runs seen from one station to the next
+func (r *RunTimeLearner) Samples(from, to string) int {
+	s, ok := r.segments[from+"-"+to]
+	if !ok {
+		return 0
+	}
+	return len(s.runs)
+}
+
+// RunTimes returns the median run and dwell times of every segment with at least minRuns
+// runs, in the order the lines reach them. Without any dwells, the default dwell is used.
+func (r *RunTimeLearner) RunTimes(minRuns int) []data.RunTime {
+	var out []data.RunTime
+	for _, key := range r.order {
+		s := r.segments[key]
+		if len(s.runs) == 0 || len(s.runs) < minRuns {
+			continue
+		}
+		rt := data.RunTime{From: s.from, To: s.to, Run: seconds(median(s.runs)), Dwell: data.DefaultRunTime.Dwell}
+		if len(s.dwell) > 0 {
+			rt.Dwell = seconds(median(s.dwell))
+		}
+		if rt.Run == 0 {
+			// too short to measure, but run times must be positive
+			rt.Run = 1
+		}
+		out = append(out, rt)
+	}
+	return out
+}
+
+func median(d []time.Duration) time.Duration {
+	sorted := make([]time.Duration, len(d))
+	copy(sorted, d)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func seconds(d time.Duration) int {
+	return int(d.Round(time.Second) / time.Second)
+}
@@ -0,0 +1,57 @@
+package recorder
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/model"
+)
+
+func TestRunTimeLearner(t *testing.T) {
+	l := data.LineNameDataPair{Name: "ew1", Line: data.Line{{Code: "EW1"}, {Code: "EW2"}, {Code: "EW3"}}}
+	learner := NewRunTimeLearner([]data.LineNameDataPair{l})
+
+	// seconds from the start a train arrives at and leaves each station, one train after another
+	trains := [][][2]int{
+		{{0, 60}, {180, 210}, {330, 360}},
+		{{300, 360}, {480, 540}, {660, 690}},
+		{{600, 660}, {780, 810}, {930, 960}},
+	}
+	start := time.Date(2022, 1, 1, 8, 0, 0, 0, time.UTC)
+	for s := 30; s <= 1200; s += 30 {
+		arrivals := make(model.Line, len(l.Line))
+		for i := range arrivals {
+			arrivals[i].Next = 9
+			for _, train := range trains {
+				arr, dep := train[i][0], train[i][1]
+				if s >= arr && s < dep {
+					arrivals[i].Next = 0
+					break
+				}
+				if s < arr {
+					arrivals[i].Next = (arr - s + 59) / 60
+					break
+				}
+			}
+		}
+		learner.Add(start.Add(time.Duration(s)*time.Second), l.Name, arrivals)
+	}
+	// a line that isn't learned is ignored
+	learner.Add(start, "ns1", model.Line{{Next: 0}})
+
+	want := []data.RunTime{
+		{From: "EW1", To: "EW2", Run: 120, Dwell: 60},
+		{From: "EW2", To: "EW3", Run: 120, Dwell: 30},
+	}
+	if got := learner.RunTimes(3); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if n := learner.Samples("EW1", "EW2"); n != 3 {
+		t.Errorf("expected 3 runs, got %d", n)
+	}
+	if got := learner.RunTimes(4); len(got) != 0 {
+		t.Errorf("expected too few runs, got %+v", got)
+	}
+}
@@ -0,0 +1,135 @@
+package recorder
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	spoolExt = ".json"
+	// files that can't be read back are renamed to this, so they don't hold up the queue
+	badSpoolExt = ".bad"
+)
+
+// Spool is an on-disk queue of snapshots that couldn't be saved, one JSON file per snapshot.
+// Replaying is safe to repeat because Store.Save skips lines that are already saved.
+type Spool struct {
+	dir string
+}
+
+func NewSpool(dir string) (*Spool, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &Spool{dir: dir}, nil
+}
+
+// Push adds a snapshot to the queue. The file only appears once it is complete.
+func (s *Spool) Push(snap Snapshot) error {
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// no-op once renamed
+		_ = os.Remove(f.Name())
+	}()
+
+	_, err = f.Write(b)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	name := strconv.FormatInt(snap.Time.UnixNano()/int64(time.Millisecond), 10) + spoolExt
+	return os.Rename(f.Name(), filepath.Join(s.dir, name))
+}
+
+// Len returns the number of queued snapshots
+func (s *Spool) Len() (int, error) {
+	names, err := s.names()
+	return len(names), err
+}
+
+// Replay saves queued snapshots into store, oldest first, removing each one once it is saved.
+// It stops at the first error saving, leaving the rest queued, and returns how many were saved.
+// A file that can't be read back is logged and moved aside with the extension .bad.
+func (s *Spool) Replay(ctx context.Context, store Store) (int, error) {
+	names, err := s.names()
+	if err != nil {
+		return 0, err
+	}
+
+	saved := 0
+	for _, name := range names {
+		path := filepath.Join(s.dir, name)
+		snap, err := readSpooled(path)
+		if err != nil {
+			log.Printf("error: spool: skipping %s: %v", name, err)
+			err = os.Rename(path, path+badSpoolExt)
+			if err != nil {
+				return saved, err
+			}
+			continue
+		}
+
+		err = store.Save(ctx, snap)
+		if err != nil {
+			return saved, err
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			return saved, err
+		}
+		saved++
+	}
+
+	return saved, nil
+}
+
+func readSpooled(path string) (Snapshot, error) {
+	var snap Snapshot
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return snap, err
+	}
+	err = json.Unmarshal(b, &snap)
+	return snap, err
+}
+
+// names lists the queued files, oldest first
+func (s *Spool) names() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), spoolExt) && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+
+	// same length until the year 2286
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,131 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// flakyStore fails every save while down is set
+type flakyStore struct {
+	Store
+	down bool
+}
+
+func (f *flakyStore) Save(ctx context.Context, s Snapshot) error {
+	if f.down {
+		return errors.New("down")
+	}
+	return f.Store.Save(ctx, s)
+}
+
+func TestSpool(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	db, err := NewSQLite(filepath.Join(dir, "recorder.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &flakyStore{Store: db, down: true}
+
+	spool, err := NewSpool(filepath.Join(dir, "spool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2021, 9, 6, 8, 0, 0, 0, time.Local)
+	for i := 0; i < 3; i++ {
+		err = spool.Push(Snapshot{
+			Time:  start.Add(time.Duration(i) * 30 * time.Second),
+			Lines: map[string]string{"ew1": "*__"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := spool.Replay(ctx, store)
+	if err == nil || n != 0 {
+		t.Errorf("expected replay to fail straight away, got %d %v", n, err)
+	}
+	if l, _ := spool.Len(); l != 3 {
+		t.Errorf("expected 3 queued, got %d", l)
+	}
+
+	store.down = false
+	// one of them made it in before, it should not be duplicated
+	err = db.Save(ctx, Snapshot{Time: start, Lines: map[string]string{"ew1": "*__"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err = spool.Replay(ctx, store)
+	if err != nil || n != 3 {
+		t.Errorf("expected 3 replayed, got %d %v", n, err)
+	}
+	if l, _ := spool.Len(); l != 0 {
+		t.Errorf("expected empty queue, got %d", l)
+	}
+
+	rows, err := db.QuerySlot(ctx, "ew1", 1, 0, 86399)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Errorf("expected 3 rows, got %d", len(rows))
+	}
+}
+
+func TestSpoolCorrupt(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	db, err := NewSQLite(filepath.Join(dir, "recorder.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spoolDir := filepath.Join(dir, "spool")
+	spool, err := NewSpool(spoolDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// truncated while it was written, and older than the good one
+	start := time.Date(2021, 9, 6, 8, 0, 0, 0, time.Local)
+	bad := filepath.Join(spoolDir, strconv.FormatInt(start.UnixNano()/int64(time.Millisecond), 10)+spoolExt)
+	err = os.WriteFile(bad, []byte(`{"time":`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = spool.Push(Snapshot{Time: start.Add(30 * time.Second), Lines: map[string]string{"ew1": "*__"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := spool.Replay(ctx, db)
+	if err != nil || n != 1 {
+		t.Errorf("expected the good snapshot replayed, got %d %v", n, err)
+	}
+	if l, _ := spool.Len(); l != 0 {
+		t.Errorf("expected empty queue, got %d", l)
+	}
+	if _, err := os.Stat(bad + badSpoolExt); err != nil {
+		t.Errorf("expected the bad file kept aside: %v", err)
+	}
+}
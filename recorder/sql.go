@@ -0,0 +1,179 @@
+package recorder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	insertColumns = " into recorded_position " +
+		"(day_of_week, seconds_of_day, time, name, line_repr, source, arrivals, tries) values "
+	insertValues = "(?,?,?,?,?,?,?,?)"
+	selectRows   = "select time, name, line_repr, source, coalesce(arrivals, '') from recorded_position "
+	querySlot    = selectRows +
+		"where name = ? and day_of_week = ? and seconds_of_day between ? and ? order by time"
+	queryRange = selectRows +
+		"where name = ? and time >= ? and time < ? order by time limit ?"
+	queryBefore = selectRows +
+		"where name = ? and time <= ? order by time desc limit 1"
+	queryAfter = selectRows +
+		"where name = ? and time > ? order by time limit 1"
+)
+
+// sqlStore is what MySQL and SQLite have in common. They only differ in how the time column is stored
+// and how to skip rows that are already there.
+type sqlStore struct {
+	db *sql.DB
+	// converts a time to what goes in the time column
+	encodeTime func(time.Time) interface{}
+	// an insert that does nothing if the row violates the time_name unique index: the start of
+	// the statement, and what goes after the values
+	insertIgnore       string
+	insertIgnoreSuffix string
+	// picks the migrations to apply
+	dialect string
+	// reports whether a migration statement failed only because it had already taken effect,
+	// nil if statements can't take effect without their migration being recorded
+	applied func(error) bool
+}
+
+// Save writes the whole snapshot in one statement and transaction, so either every line is saved
+// or none are. Lines that were already saved for the same time are left alone, so a snapshot
+// can be saved again safely if it is not known whether the first attempt went through.
+func (s *sqlStore) Save(ctx context.Context, snap Snapshot) error {
+	return s.save(ctx, s.insertIgnore, s.insertIgnoreSuffix, snap)
+}
+
+// Replace relies on the unique index on time and name, both backends understand replace into
+func (s *sqlStore) Replace(ctx context.Context, snap Snapshot) error {
+	return s.save(ctx, "replace", "", snap)
+}
+
+func (s *sqlStore) save(ctx context.Context, verb, suffix string, snap Snapshot) error {
+	if len(snap.Lines) == 0 {
+		return nil
+	}
+
+	dayOfWeek, secondsOfDay := slotOf(snap.Time)
+	t := s.encodeTime(snap.Time)
+
+	source := snap.Source
+	if source == "" {
+		source = SourceLive
+	}
+	var tries sql.NullInt64
+	if snap.Tries > 0 {
+		tries = sql.NullInt64{Int64: int64(snap.Tries), Valid: true}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(verb)
+	sb.WriteString(insertColumns)
+	args := make([]interface{}, 0, len(snap.Lines)*8)
+	for name, linerepr := range snap.Lines {
+		if len(args) > 0 {
+			sb.WriteRune(',')
+		}
+		sb.WriteString(insertValues)
+		var arrivals sql.NullString
+		if a, ok := snap.Arrivals[name]; ok {
+			arrivals = sql.NullString{String: a, Valid: true}
+		}
+		args = append(args, dayOfWeek, secondsOfDay, t, name, linerepr, source, arrivals, tries)
+	}
+	sb.WriteString(suffix)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, sb.String(), args...)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlStore) QuerySlot(ctx context.Context, name string, dayOfWeek, fromSecond, toSecond int) ([]Row, error) {
+	return s.query(ctx, querySlot, name, dayOfWeek, fromSecond, toSecond)
+}
+
+func (s *sqlStore) QueryRange(ctx context.Context, name string, from, to time.Time, limit int) ([]Row, error) {
+	return s.query(ctx, queryRange, name, s.encodeTime(from), s.encodeTime(to), limit)
+}
+
+// Nearest returns the row of a line closest in time to t, before or after.
+// The bool is false if the line was never recorded.
+func (s *sqlStore) Nearest(ctx context.Context, name string, t time.Time) (Row, bool, error) {
+	before, err := s.query(ctx, queryBefore, name, s.encodeTime(t))
+	if err != nil {
+		return Row{}, false, err
+	}
+	after, err := s.query(ctx, queryAfter, name, s.encodeTime(t))
+	if err != nil {
+		return Row{}, false, err
+	}
+
+	switch {
+	case len(before) == 0 && len(after) == 0:
+		return Row{}, false, nil
+	case len(after) == 0:
+		return before[0], true, nil
+	case len(before) == 0:
+		return after[0], true, nil
+	case t.Sub(before[0].Time) <= after[0].Time.Sub(t):
+		return before[0], true, nil
+	default:
+		return after[0], true, nil
+	}
+}
+
+func (s *sqlStore) query(ctx context.Context, query string, args ...interface{}) ([]Row, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Row
+	for rows.Next() {
+		var r Row
+		err = rows.Scan(timeScanner{&r.Time}, &r.Name, &r.Repr, &r.Source, &r.Arrivals)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+
+	return out, rows.Err()
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// timeScanner reads the time column whether it holds a timestamp or unix milliseconds.
+// NULL is read as the zero time.
+type timeScanner struct {
+	t *time.Time
+}
+
+func (ts timeScanner) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*ts.t = time.Time{}
+	case time.Time:
+		*ts.t = v
+	case int64:
+		*ts.t = time.Unix(0, v*int64(time.Millisecond))
+	default:
+		return fmt.Errorf("cannot scan %T into time", src)
+	}
+	return nil
+}
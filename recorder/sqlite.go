@@ -0,0 +1,36 @@
+package recorder
+
+import (
+	"database/sql"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLite keeps recordings in a local file, for when there is no database server around
+type SQLite struct {
+	sqlStore
+}
+
+var _ Store = (*SQLite)(nil)
+
+// NewSQLite opens or creates the database at path. It is empty until Migrate is called.
+func NewSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	// sqlite only allows one writer anyway
+	db.SetMaxOpenConns(1)
+
+	return &SQLite{
+		sqlStore{
+			db: db,
+			encodeTime: func(t time.Time) interface{} {
+				return t.UnixNano() / int64(time.Millisecond)
+			},
+			insertIgnore: "insert or ignore",
+			dialect:      BackendSQLite,
+		},
+	}, nil
+}
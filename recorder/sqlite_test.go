@@ -0,0 +1,148 @@
+package recorder
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLite(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "recorder.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	n, err := s.Migrate(ctx)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 migrations, got %d %v", n, err)
+	}
+	n, err = s.Migrate(ctx)
+	if err != nil || n != 0 {
+		t.Fatalf("expected no more migrations, got %d %v", n, err)
+	}
+
+	// a monday
+	start := time.Date(2021, 9, 6, 8, 0, 0, 500000000, time.Local)
+	for i := 0; i < 10; i++ {
+		err = s.Save(ctx, Snapshot{
+			Time: start.Add(time.Duration(i) * 30 * time.Second),
+			Lines: map[string]string{
+				"ew1": "*__",
+				"ew2": "__*",
+			},
+			Arrivals: map[string]string{
+				"ew1": "0/4,2/5",
+			},
+			Tries: 12,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// saving again is a no-op for lines already there
+	err = s.Save(ctx, Snapshot{Time: start, Lines: map[string]string{"ew1": "___", "cg1": "_*_"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := s.QueryRange(ctx, "ew1", start, start.Add(time.Second), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Repr != "*__" {
+		t.Errorf("expected the first save to be kept, got %+v", rows)
+	}
+	rows, err = s.QueryRange(ctx, "cg1", start, start.Add(time.Second), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Errorf("expected the new line to be saved, got %+v", rows)
+	}
+
+	err = s.Replace(ctx, Snapshot{Time: start, Lines: map[string]string{"ew1": "___"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err = s.QueryRange(ctx, "ew1", start, start.Add(time.Second), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Repr != "___" {
+		t.Errorf("expected replace to overwrite, got %+v", rows)
+	}
+
+	rows, err = s.QueryRange(ctx, "ew1", start.Add(time.Minute), start.Add(3*time.Minute), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(rows))
+	}
+	if !rows[0].Time.Equal(start.Add(time.Minute)) || rows[0].Name != "ew1" || rows[0].Repr != "*__" ||
+		rows[0].Source != SourceLive || rows[0].Arrivals != "0/4,2/5" {
+		t.Errorf("unexpected first row %+v", rows[0])
+	}
+
+	rows, err = s.QueryRange(ctx, "ew2", start, start.Add(time.Hour), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Errorf("expected limit of 3 rows, got %d", len(rows))
+	}
+
+	rows, err = s.QuerySlot(ctx, "ew2", 1, 8*3600+60, 8*3600+120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Errorf("expected 3 rows in slot, got %d", len(rows))
+	}
+
+	row, ok, err := s.Nearest(ctx, "ew1", start.Add(40*time.Second))
+	if err != nil || !ok || !row.Time.Equal(start.Add(30*time.Second)) {
+		t.Errorf("unexpected nearest row %+v %v %v", row, ok, err)
+	}
+	row, ok, err = s.Nearest(ctx, "ew1", start.Add(-time.Hour))
+	if err != nil || !ok || !row.Time.Equal(start) {
+		t.Errorf("unexpected nearest row before recording %+v %v %v", row, ok, err)
+	}
+	_, ok, err = s.Nearest(ctx, "ns1", start)
+	if err != nil || ok {
+		t.Errorf("expected no rows for an unrecorded line, got %v %v", ok, err)
+	}
+}
+
+func TestMigrateRerun(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "recorder.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	_, err = s.Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// as if the statements of the later migrations were committed but not recorded
+	_, err = s.db.ExecContext(ctx, "delete from schema_version where version >= 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Migrate(ctx); err == nil {
+		t.Fatal("expected the columns to be there already")
+	}
+
+	s.applied = func(err error) bool { return strings.Contains(err.Error(), "duplicate column name") }
+	n, err := s.Migrate(ctx)
+	if err != nil || n != 2 {
+		t.Errorf("expected 2 migrations, got %d %v", n, err)
+	}
+}
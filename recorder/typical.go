@@ -0,0 +1,78 @@
+package recorder
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/model"
+)
+
+const queryTypicalRows = "select line_repr from recorded_position " +
+	"where name = ? and day_of_week = ? and seconds_of_day between ? and ? and time >= ? " +
+	"order by time"
+
+// Typical builds the typical occupancy of a line in the slot containing secondsOfDay on dayOfWeek,
+// over every recorded week. It combines the aggregates made by Retain with the raw rows that
+// haven't been aggregated yet, so it works whether or not Retain has ever been run.
+// If nothing was recorded in the slot, the aggregate has no samples.
+func (s *sqlStore) Typical(ctx context.Context, name string, dayOfWeek, secondsOfDay int) (Aggregate, error) {
+	slotSeconds, ok, err := s.state(ctx, stateSlotSeconds)
+	if err != nil {
+		return Aggregate{}, err
+	}
+	if !ok {
+		slotSeconds = int64(defaultSlot / time.Second)
+	}
+
+	agg := Aggregate{
+		Name:      name,
+		DayOfWeek: dayOfWeek,
+		Slot:      secondsOfDay - secondsOfDay%int(slotSeconds),
+	}
+
+	var counts string
+	err = s.db.QueryRowContext(ctx, queryAggregate, name, dayOfWeek, agg.Slot).Scan(&agg.Samples, &counts)
+	if err == nil {
+		agg.Counts, err = decodeCounts(counts)
+		if err != nil {
+			return Aggregate{}, err
+		}
+	} else if err != sql.ErrNoRows {
+		return Aggregate{}, err
+	}
+
+	untilMs, _, err := s.state(ctx, stateAggregatedUntil)
+	if err != nil {
+		return Aggregate{}, err
+	}
+	until := time.Unix(0, untilMs*int64(time.Millisecond))
+
+	rows, err := s.db.QueryContext(ctx, queryTypicalRows,
+		name, dayOfWeek, agg.Slot, agg.Slot+int(slotSeconds)-1, s.encodeTime(until))
+	if err != nil {
+		return Aggregate{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var repr string
+		err = rows.Scan(&repr)
+		if err != nil {
+			return Aggregate{}, err
+		}
+
+		p, err := model.NewPositionFromString(repr)
+		if err != nil {
+			continue
+		}
+		if len(agg.Counts) != len(p) {
+			// the line changed, rows are oldest first so only the newer shape is kept
+			agg.Samples = 0
+			agg.Counts = make([]int, len(p))
+		}
+		agg.add(p)
+	}
+
+	return agg, rows.Err()
+}
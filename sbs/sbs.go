@@ -1,3 +1,167 @@
+// Package sbs gets arrivals for the lines SBS Transit runs (NEL, DTL, CCL and the Sengkang and
+// Punggol LRT here) from a JSON source. SBS has no public arrivals API like smrt's, so the source
+// is configurable: anything that serves the format below, like a scraper or a proxy in front of
+// another feed.
+//
+//	{
+//	  "stations": {
+//	    "Dhoby Ghaut": [
+//	      {"platform": "DBG_E", "trains": [{"destination": "Punggol", "minutes": 2}, {"destination": "Punggol", "minutes": 7}]},
+//	      ...
+//
+// minutes is 0 for a train at the platform and missing when unknown. Platform IDs must match the
+// line data.
 package sbs
 
-// TODO: If SBS even has this data available
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+type document struct {
+	Stations map[string][]platform `json:"stations"`
+}
+
+type platform struct {
+	Platform string  `json:"platform"`
+	Trains   []train `json:"trains"`
+}
+
+type train struct {
+	Destination string `json:"destination"`
+	Minutes     *int   `json:"minutes"`
+}
+
+// Provider fetches the whole document once per call, and keeps the stations asked for
+type Provider struct {
+	URL string
+	// Sent as the Authorization header if not empty
+	Key string
+	// Total requests per call, including retries. Defaults to 1.
+	MaxTries int
+	// Defaults to http.DefaultClient
+	Client *http.Client
+}
+
+func (p *Provider) Arrivals(ctx context.Context, stations []string) (map[string]smrt.Result, int64, error) {
+	doc, tries, err := p.fetch(ctx)
+	if err != nil {
+		return nil, tries, err
+	}
+
+	out := make(map[string]smrt.Result)
+	for _, name := range stations {
+		platforms, ok := doc.Stations[name]
+		if !ok {
+			continue
+		}
+		res := make(smrt.Result, 0, len(platforms))
+		for _, pl := range platforms {
+			res = append(res, pl.normalise(name))
+		}
+		out[name] = res
+	}
+	return out, tries, nil
+}
+
+func (p *Provider) fetch(ctx context.Context) (*document, int64, error) {
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	maxTries := p.MaxTries
+	if maxTries <= 0 {
+		maxTries = 1
+	}
+
+	var tries int64
+	var err error
+	for tries < int64(maxTries) {
+		tries++
+		var doc *document
+		doc, err = p.fetchOnce(ctx, client)
+		if err == nil {
+			return doc, tries, nil
+		}
+		if tries == int64(maxTries) {
+			break
+		}
+		t := time.NewTimer(100 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, tries, err
+		case <-t.C:
+		}
+	}
+	return nil, tries, err
+}
+
+func (p *Provider) fetchOnce(ctx context.Context, client *http.Client) (*document, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.Key != "" {
+		req.Header.Set("Authorization", p.Key)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc document
+	err = json.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// normalise converts a platform to the smrt shape
+func (pl platform) normalise(station string) smrt.NextTrains {
+	out := smrt.NextTrains{
+		Mrt:          station,
+		PlatformID:   pl.Platform,
+		Status:       1,
+		NextTrainArr: "N/A",
+	}
+	if len(pl.Trains) > 0 {
+		out.NextTrainArr = arr(pl.Trains[0].Minutes)
+		out.NextTrainDestination = pl.Trains[0].Destination
+	}
+	if len(pl.Trains) > 1 {
+		out.SubseqTrainArr = arr(pl.Trains[1].Minutes)
+		out.SubseqTrainDestination = pl.Trains[1].Destination
+	}
+	return out
+}
+
+func arr(minutes *int) string {
+	switch {
+	case minutes == nil || *minutes < 0:
+		return "N/A"
+	case *minutes == 0:
+		return "Arr"
+	default:
+		return strconv.Itoa(*minutes)
+	}
+}
@@ -0,0 +1,75 @@
+package sbs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+const fixture = `{
+  "stations": {
+    "Dhoby Ghaut": [
+      {"platform": "DBG_E", "trains": [{"destination": "Punggol", "minutes": 0}, {"destination": "Punggol", "minutes": 6}]},
+      {"platform": "DBG_F", "trains": [{"destination": "HarbourFront"}]}
+    ],
+    "Little India": [
+      {"platform": "LTI_A", "trains": []}
+    ]
+  }
+}`
+
+func TestProvider(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if requests == 1 {
+			// flaky upstream, retried
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(fixture))
+	}))
+	defer srv.Close()
+
+	p := &Provider{URL: srv.URL, Key: "secret", MaxTries: 2}
+	results, tries, err := p.Arrivals(context.Background(), []string{"Dhoby Ghaut", "Farrer Park"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tries != 2 {
+		t.Errorf("got %d tries, want 2", tries)
+	}
+	if _, ok := results["Farrer Park"]; ok {
+		t.Error("Farrer Park isn't in the source")
+	}
+	if _, ok := results["Little India"]; ok {
+		t.Error("Little India wasn't asked for")
+	}
+
+	// normalised so the rest of the tracker can read it
+	line := data.Line{
+		{Code: "NE6", Code3: "DBG", Platform: "E", Name: "Dhoby Ghaut"},
+		{Code: "NE6", Code3: "DBG", Platform: "F", Name: "Dhoby Ghaut"},
+	}
+	ml := smrt.ToModel(results, line)
+	if ml[0].Next != 0 || ml[0].Subseq != 6 || ml[0].Dest != "Punggol" {
+		t.Errorf("DBG_E: got %+v", ml[0])
+	}
+	if ml[1].Next != -1 || ml[1].Subseq != -1 || ml[1].Dest != "HarbourFront" {
+		t.Errorf("DBG_F: got %+v", ml[1])
+	}
+
+	p.Key = "wrong"
+	p.MaxTries = 1
+	if _, _, err = p.Arrivals(context.Background(), []string{"Dhoby Ghaut"}); err == nil {
+		t.Error("expected an error when unauthorised")
+	}
+}
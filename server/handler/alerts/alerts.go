@@ -0,0 +1,283 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/recorder"
+	"go.lepak.sg/mrtracker-backend/server/handler/position"
+)
+
+const (
+	defaultMinRun        = 3
+	defaultStuckFor      = 5 * time.Minute
+	defaultHeadwayFactor = 2
+	defaultWindow        = 15 * time.Minute
+)
+
+const (
+	KindSuspended = "suspended"
+	KindStuck     = "stuck"
+	KindHeadway   = "headway"
+)
+
+const (
+	SeverityMinor    = "minor"
+	SeverityMajor    = "major"
+	SeverityCritical = "critical"
+)
+
+// History is where the usual headway for a time slot comes from
+type History interface {
+	QuerySlot(ctx context.Context, name string, dayOfWeek, fromSecond, toSecond int) ([]recorder.Row, error)
+}
+
+type Config struct {
+	// Consecutive stations without service before it counts as a suspension
+	MinRun int
+	// How long a train can stay in the same place
+	StuckFor time.Duration
+	// How many times the usual headway is too long
+	HeadwayFactor float64
+	// The usual headway is taken from recordings this far either side of the current time
+	Window time.Duration
+	// If nil, headways are not checked
+	History History
+}
+
+type Station struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type Alert struct {
+	Line      string  `json:"line"`
+	Kind      string  `json:"kind"`
+	Severity  string  `json:"severity"`
+	From      Station `json:"from"`
+	To        Station `json:"to"`
+	StartedAt uint64  `json:"started_at"`
+	Message   string  `json:"message"`
+}
+
+type result struct {
+	Alerts      []Alert `json:"alerts"`
+	LastUpdated uint64  `json:"last_updated"`
+}
+
+type alertKey struct {
+	line string
+	kind string
+	span span
+}
+
+type baseline struct {
+	bucket  int
+	headway float64
+	ok      bool
+}
+
+// Handler infers likely disruptions from every update and serves the current ones.
+type Handler struct {
+	c        Config
+	stations map[string]data.Line
+
+	// only touched by Observe
+	occupiedSince map[string][]time.Time
+	started       map[alertKey]time.Time
+	baselines     map[string]baseline
+
+	lock        sync.RWMutex
+	alerts      []Alert
+	lastUpdated time.Time
+}
+
+var _ position.Observer = (*Handler)(nil)
+
+func New(c Config) *Handler {
+	if c.MinRun <= 0 {
+		c.MinRun = defaultMinRun
+	}
+	if c.StuckFor <= 0 {
+		c.StuckFor = defaultStuckFor
+	}
+	if c.HeadwayFactor <= 1 {
+		c.HeadwayFactor = defaultHeadwayFactor
+	}
+	if c.Window <= 0 {
+		c.Window = defaultWindow
+	}
+
+	h := &Handler{
+		c:             c,
+		stations:      make(map[string]data.Line),
+		occupiedSince: make(map[string][]time.Time),
+		started:       make(map[alertKey]time.Time),
+		baselines:     make(map[string]baseline),
+		alerts:        []Alert{},
+	}
+
+	for _, l := range data.GetLines() {
+		h.stations[l.Name] = l.Line
+	}
+
+	return h
+}
+
+func (h *Handler) Observe(ctx context.Context, u *position.Update) {
+	alerts := []Alert{}
+	seen := make(map[alertKey]bool)
+
+	add := func(k alertKey, severity, msg string) {
+		seen[k] = true
+		started, ok := h.started[k]
+		if !ok {
+			started = u.Time
+			h.started[k] = started
+		}
+		stations := h.stations[k.line]
+		alerts = append(alerts, Alert{
+			Line:      k.line,
+			Kind:      k.kind,
+			Severity:  severity,
+			From:      Station{stations[k.span.from].Code, stations[k.span.from].Name},
+			To:        Station{stations[k.span.to].Code, stations[k.span.to].Name},
+			StartedAt: uint64(started.UnixNano() / 1000000),
+			Message:   msg,
+		})
+	}
+
+	for _, dl := range data.GetLines() {
+		l, ok := u.Lines[dl.Name]
+		if !ok || len(l) != len(dl.Line) {
+			continue
+		}
+
+		for _, s := range suspendedRuns(l, h.c.MinRun) {
+			severity := SeverityMajor
+			if s.from == 0 && s.to == len(l)-1 {
+				severity = SeverityCritical
+			}
+			add(alertKey{dl.Name, KindSuspended, s}, severity,
+				fmt.Sprintf("no service at %d stations", s.to-s.from+1))
+		}
+
+		if p, ok := u.Positions[dl.Name]; ok {
+			since := h.occupiedSince[dl.Name]
+			if len(since) != len(p) {
+				since = make([]time.Time, len(p))
+				h.occupiedSince[dl.Name] = since
+			}
+			trackOccupancy(since, p, u.Time)
+
+			// trains wait at the termini, don't count those
+			for i := 1; i < len(p)-1; i++ {
+				if since[i].IsZero() {
+					continue
+				}
+				d := u.Time.Sub(since[i])
+				if d < h.c.StuckFor {
+					continue
+				}
+				severity := SeverityMinor
+				if d >= 2*h.c.StuckFor {
+					severity = SeverityMajor
+				}
+				add(alertKey{dl.Name, KindStuck, positionSpan(i)}, severity,
+					fmt.Sprintf("train has not moved for %d min", int(d.Minutes())))
+			}
+		}
+
+		current := model.SummarizeHeadways(l.Headways())
+		if usual, ok := h.usualHeadway(ctx, dl.Name, u.Time); ok && current.Count > 0 &&
+			current.P50 >= usual*h.c.HeadwayFactor {
+			severity := SeverityMinor
+			if current.P50 >= usual*h.c.HeadwayFactor*2 {
+				severity = SeverityMajor
+			}
+			add(alertKey{dl.Name, KindHeadway, span{0, len(l) - 1}}, severity,
+				fmt.Sprintf("trains every %.0f min, usually %.0f min", current.P50, usual))
+		}
+	}
+
+	for k := range h.started {
+		if !seen[k] {
+			delete(h.started, k)
+		}
+	}
+
+	h.lock.Lock()
+	h.alerts = alerts
+	h.lastUpdated = u.Time
+	h.lock.Unlock()
+}
+
+// usualHeadway looks up the historical headway around t, caching it until t moves to another window
+func (h *Handler) usualHeadway(ctx context.Context, line string, t time.Time) (float64, bool) {
+	if h.c.History == nil {
+		return 0, false
+	}
+
+	window := int(h.c.Window.Seconds())
+	secondsOfDay := t.Hour()*3600 + t.Minute()*60 + t.Second()
+	bucket := int(t.Weekday())*86400 + secondsOfDay - secondsOfDay%window
+
+	if b, ok := h.baselines[line]; ok && b.bucket == bucket {
+		return b.headway, b.ok
+	}
+
+	b := baseline{bucket: bucket}
+	var rows []recorder.Row
+	var err error
+	for _, s := range slotsAround(int(t.Weekday()), secondsOfDay, window) {
+		var slotRows []recorder.Row
+		slotRows, err = h.c.History.QuerySlot(ctx, line, s.dayOfWeek, s.from, s.to)
+		if err != nil {
+			break
+		}
+		rows = append(rows, slotRows...)
+	}
+	if err != nil {
+		// try again in the next window
+		log.Printf("error: alerts: querying history for %s: %v", line, err)
+	} else {
+		b.headway, b.ok = historicalHeadway(rows)
+	}
+	h.baselines[line] = b
+
+	return b.headway, b.ok
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	h.lock.RLock()
+	res := result{
+		Alerts:      h.alerts,
+		LastUpdated: uint64(h.lastUpdated.UnixNano() / 1000000),
+	}
+	marshal, err := json.Marshal(res)
+	h.lock.RUnlock()
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		errstr := fmt.Sprintf("{\"error\":%q}", err.Error())
+		_, err2 := w.Write([]byte(errstr))
+		if err2 != nil {
+			log.Printf("error: double fault in alerts handler: %v -> %v", err, err2)
+		}
+		return
+	}
+
+	_, err = w.Write(marshal)
+	if err != nil {
+		log.Printf("error: %v", err)
+	}
+}
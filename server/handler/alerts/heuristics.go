@@ -0,0 +1,158 @@
+package alerts
+
+import (
+	"sort"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/recorder"
+)
+
+const destDoNotBoard = "Do not board"
+
+// span is an inclusive range of station indices
+type span struct {
+	from, to int
+}
+
+// suspendedRuns finds runs of at least minRun consecutive stations that either have no arrival
+// time or tell passengers not to board.
+func suspendedRuns(l model.Line, minRun int) []span {
+	var out []span
+	start := -1
+
+	for i := 0; i <= len(l); i++ {
+		if i < len(l) && (l[i].Next == -1 || l[i].Dest == destDoNotBoard) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= minRun {
+			out = append(out, span{start, i - 1})
+		}
+		start = -1
+	}
+
+	return out
+}
+
+// positionSpan maps an index into a Position to the stations around it
+func positionSpan(i int) span {
+	if i%2 == 0 {
+		return span{i / 2, i / 2}
+	}
+	return span{(i - 1) / 2, (i + 1) / 2}
+}
+
+// trackOccupancy updates since with the time each position has been occupied from.
+// Zero means unoccupied. since must be as long as p.
+func trackOccupancy(since []time.Time, p model.Position, now time.Time) {
+	for i := range p {
+		if !p[i] {
+			since[i] = time.Time{}
+		} else if since[i].IsZero() {
+			since[i] = now
+		}
+	}
+}
+
+// slot is a range of seconds of one day of the week, inclusive, as taken by History.QuerySlot
+type slot struct {
+	dayOfWeek, from, to int
+}
+
+// slotsAround returns the slots covering window seconds either side of secondsOfDay on
+// dayOfWeek. Near midnight the range is split, with the part past it on the adjacent day.
+func slotsAround(dayOfWeek, secondsOfDay, window int) []slot {
+	const day = 24 * 60 * 60
+	from, to := secondsOfDay-window, secondsOfDay+window
+
+	out := []slot{{dayOfWeek, maxInt(from, 0), minInt(to, day-1)}}
+	if from < 0 {
+		out = append(out, slot{(dayOfWeek + 6) % 7, day + from, day - 1})
+	}
+	if to >= day {
+		out = append(out, slot{(dayOfWeek + 1) % 7, 0, to - day})
+	}
+	return out
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// historicalHeadway estimates the median headway in minutes from recorded positions of one line.
+// Rows are grouped by date into runs. Within a run, a train arriving at a platform shows up as
+// that platform going from empty to occupied, so the headway at a platform is the length of the
+// run divided by the number of arrivals seen there. Trains that stop for less than the recording
+// interval are missed, so this errs on the long side.
+func historicalHeadway(rows []recorder.Row) (float64, bool) {
+	runs := make(map[string][]recorder.Row)
+	for _, r := range rows {
+		date := r.Time.Format("2006-01-02")
+		runs[date] = append(runs[date], r)
+	}
+
+	var headways []float64
+	for _, run := range runs {
+		if len(run) < 2 {
+			continue
+		}
+		sort.Slice(run, func(i, j int) bool { return run[i].Time.Before(run[j].Time) })
+
+		minutes := run[len(run)-1].Time.Sub(run[0].Time).Minutes()
+		if minutes <= 0 {
+			continue
+		}
+
+		var arrivals []int
+		var prev model.Position
+		for _, r := range run {
+			p, err := model.NewPositionFromString(r.Repr)
+			if err != nil {
+				continue
+			}
+			if arrivals == nil {
+				arrivals = make([]int, len(p))
+			}
+			if len(p) != len(arrivals) {
+				// line changed under us
+				continue
+			}
+			for i := 0; i < len(p); i += 2 {
+				if p[i] && prev != nil && !prev[i] {
+					arrivals[i]++
+				}
+			}
+			prev = p
+		}
+
+		for _, n := range arrivals {
+			if n > 0 {
+				headways = append(headways, minutes/float64(n))
+			}
+		}
+	}
+
+	if len(headways) == 0 {
+		return 0, false
+	}
+
+	sort.Float64s(headways)
+	mid := len(headways) / 2
+	if len(headways)%2 == 0 {
+		return (headways[mid-1] + headways[mid]) / 2, true
+	}
+	return headways[mid], true
+}
@@ -0,0 +1,77 @@
+package alerts
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/recorder"
+)
+
+func Test_suspendedRuns(t *testing.T) {
+	l := model.Line{
+		{Next: -1},
+		{Next: 2},
+		{Next: -1},
+		{Next: 3, Dest: destDoNotBoard},
+		{Next: -1},
+		{Next: 1},
+		{Next: -1},
+		{Next: -1},
+		{Next: -1},
+	}
+
+	runs := suspendedRuns(l, 3)
+	if expected := []span{{2, 4}, {6, 8}}; !reflect.DeepEqual(runs, expected) {
+		t.Errorf("expected %v, got %v", expected, runs)
+	}
+}
+
+func Test_historicalHeadway(t *testing.T) {
+	start := time.Date(2021, 9, 6, 8, 0, 0, 0, time.UTC)
+	var rows []recorder.Row
+
+	// two platforms, a train reaches one every 4 minutes and the other every 8 over 16 minutes
+	for i := 0; i <= 32; i++ {
+		repr := []byte("___")
+		if i%8 == 1 {
+			repr[0] = '*'
+		}
+		if i%16 == 1 {
+			repr[2] = '*'
+		}
+		rows = append(rows, recorder.Row{
+			Time: start.Add(time.Duration(i) * 30 * time.Second),
+			Name: "cg1",
+			Repr: string(repr),
+		})
+	}
+
+	h, ok := historicalHeadway(rows)
+	if !ok {
+		t.Fatal("no headway")
+	}
+	if h != 6 {
+		// 16/4 and 16/2
+		t.Errorf("expected 6, got %f", h)
+	}
+}
+
+func Test_slotsAround(t *testing.T) {
+	cases := []struct {
+		day, seconds int
+		want         []slot
+	}{
+		{1, 43200, []slot{{1, 42300, 44100}}},
+		// just after midnight on Monday, the rest is late on Sunday
+		{1, 300, []slot{{1, 0, 1200}, {0, 85800, 86399}}},
+		// just before midnight on Saturday, the rest is early on Sunday
+		{6, 86000, []slot{{6, 85100, 86399}, {0, 0, 500}}},
+	}
+	for _, c := range cases {
+		if got := slotsAround(c.day, c.seconds, 900); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%d %d: got %v, want %v", c.day, c.seconds, got, c.want)
+		}
+	}
+}
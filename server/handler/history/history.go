@@ -0,0 +1,218 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/recorder"
+	"go.lepak.sg/mrtracker-backend/server/handler/param"
+)
+
+const (
+	defaultStep     = 30 * time.Second
+	defaultLimit    = 2880 // one day at the recorder's interval
+	maxLimit        = 20000
+	maxRange        = 7 * 24 * time.Hour
+	queryTimeout    = 10 * time.Second
+	maxRowsPerQuery = 100000
+)
+
+// Store is where recorded positions are read from
+type Store interface {
+	QueryRange(ctx context.Context, name string, from, to time.Time, limit int) ([]recorder.Row, error)
+}
+
+// Handler serves recorded positions for one line over a time range:
+//
+//	/v1/history/position?line=ew1&from=<time>&to=<time>&step=30s&limit=2880
+//
+// Times are unix milliseconds or RFC 3339. to defaults to now. At most one row is returned per step.
+type Handler struct {
+	store Store
+	lines map[string]bool
+}
+
+type result struct {
+	Line string `json:"line"`
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+	Step uint64 `json:"step"`
+	// More rows were available than the limit
+	Truncated bool    `json:"truncated"`
+	Positions []entry `json:"positions"`
+}
+
+type entry struct {
+	Time      uint64 `json:"time"`
+	Positions string `json:"positions"`
+}
+
+func New(store Store) *Handler {
+	h := &Handler{
+		store: store,
+		lines: make(map[string]bool),
+	}
+
+	for _, l := range data.GetLines() {
+		h.lines[l.Name] = true
+	}
+
+	return h
+}
+
+type params struct {
+	line     string
+	from, to time.Time
+	step     time.Duration
+	limit    int
+}
+
+func (h *Handler) parseParams(r *http.Request) (*params, error) {
+	q := r.URL.Query()
+	p := &params{
+		line:  q.Get("line"),
+		to:    time.Now(),
+		step:  defaultStep,
+		limit: defaultLimit,
+	}
+
+	if !h.lines[p.line] {
+		return nil, fmt.Errorf("unknown line: %q", p.line)
+	}
+
+	var err error
+	if s := q.Get("from"); s != "" {
+		p.from, err = param.ParseTime(s)
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+	} else {
+		return nil, errors.New("from is required")
+	}
+
+	if s := q.Get("to"); s != "" {
+		p.to, err = param.ParseTime(s)
+		if err != nil {
+			return nil, fmt.Errorf("to: %w", err)
+		}
+	}
+
+	if !p.to.After(p.from) {
+		return nil, errors.New("to must be after from")
+	}
+	if p.to.Sub(p.from) > maxRange {
+		return nil, fmt.Errorf("range longer than %s", maxRange)
+	}
+
+	if s := q.Get("step"); s != "" {
+		p.step, err = time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("step: %w", err)
+		}
+		if p.step < time.Second {
+			return nil, errors.New("step must be at least 1s")
+		}
+	}
+
+	if s := q.Get("limit"); s != "" {
+		p.limit, err = strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("limit: %w", err)
+		}
+		if p.limit <= 0 || p.limit > maxLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+	}
+
+	return p, nil
+}
+
+func (h *Handler) resultForParams(ctx context.Context, p *params) (*result, error) {
+	rows, err := h.store.QueryRange(ctx, p.line, p.from, p.to, maxRowsPerQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &result{
+		Line:      p.line,
+		From:      toMillis(p.from),
+		To:        toMillis(p.to),
+		Step:      uint64(p.step / time.Millisecond),
+		Positions: []entry{},
+	}
+
+	// keep the first row in every step
+	lastSlot := int64(-1)
+	for _, row := range rows {
+		slot := int64(row.Time.Sub(p.from) / p.step)
+		if slot <= lastSlot {
+			continue
+		}
+		if len(out.Positions) == p.limit {
+			out.Truncated = true
+			break
+		}
+		lastSlot = slot
+		out.Positions = append(out.Positions, entry{
+			Time:      toMillis(row.Time),
+			Positions: row.Repr,
+		})
+	}
+
+	if len(rows) == maxRowsPerQuery && !out.Truncated {
+		out.Truncated = true
+	}
+
+	return out, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	p, err := h.parseParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+	defer cancel()
+
+	res, err := h.resultForParams(ctx, p)
+	if err != nil {
+		log.Printf("error: history query: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	marshal, err := json.Marshal(res)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, err = w.Write(marshal)
+	if err != nil {
+		log.Printf("error: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.WriteHeader(code)
+	errstr := fmt.Sprintf("{\"error\":%q}", err.Error())
+	_, err2 := w.Write([]byte(errstr))
+	if err2 != nil {
+		log.Printf("error: double fault in history handler: %v -> %v", err, err2)
+	}
+}
+
+func toMillis(t time.Time) uint64 {
+	return uint64(t.UnixNano() / 1000000)
+}
@@ -0,0 +1,75 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/recorder"
+)
+
+type fakeStore []recorder.Row
+
+func (f fakeStore) QueryRange(_ context.Context, name string, from, to time.Time, limit int) ([]recorder.Row, error) {
+	var out []recorder.Row
+	for _, r := range f {
+		if r.Name == name && !r.Time.Before(from) && r.Time.Before(to) && len(out) < limit {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func TestHandler(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	var store fakeStore
+	for i := 0; i < 20; i++ {
+		store = append(store, recorder.Row{
+			Time: start.Add(time.Duration(i) * 30 * time.Second),
+			Name: "ew1",
+			Repr: "*_",
+		})
+	}
+
+	h := New(store)
+
+	tests := []struct {
+		query     string
+		code      int
+		count     int
+		truncated bool
+	}{
+		{"line=ew1&from=1600000000000&to=1600000600000", http.StatusOK, 20, false},
+		{"line=ew1&from=1600000000000&to=1600000600000&step=2m", http.StatusOK, 5, false},
+		{"line=ew1&from=2020-09-13T12:26:40Z&to=2020-09-13T12:36:40Z&step=1m&limit=3", http.StatusOK, 3, true},
+		{"line=ew1&from=1600000300000&to=1600000600000", http.StatusOK, 10, false},
+		{"line=xx1&from=1600000000000", http.StatusBadRequest, 0, false},
+		{"line=ew1", http.StatusBadRequest, 0, false},
+		{"line=ew1&from=1600000600000&to=1600000000000", http.StatusBadRequest, 0, false},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/history/position?"+tt.query, nil))
+
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d: %s", tt.query, tt.code, w.Code, w.Body)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+
+		var res result
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Positions) != tt.count || res.Truncated != tt.truncated {
+			t.Errorf("%s: expected %d rows truncated=%t, got %d truncated=%t",
+				tt.query, tt.count, tt.truncated, len(res.Positions), res.Truncated)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package lines
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/server/handler/position"
+)
+
+// Prefix is where the handler should be mounted. Routes below it:
+//
+//	<name>/headways
+const Prefix = "/v1/lines/"
+
+// Handler serves per-line details worked out from the latest update.
+// It has to be registered as an observer on the position handler to get any data.
+type Handler struct {
+	lock        sync.RWMutex
+	lines       map[string]model.Line
+	lastUpdated time.Time
+
+	stations map[string]data.Line
+	metrics  *metrics
+}
+
+var _ position.Observer = (*Handler)(nil)
+
+type headwayResult struct {
+	Line        string           `json:"line"`
+	LastUpdated uint64           `json:"last_updated"`
+	Count       int              `json:"count"`
+	Min         *float64         `json:"min,omitempty"`
+	P50         *float64         `json:"p50,omitempty"`
+	P90         *float64         `json:"p90,omitempty"`
+	Max         *float64         `json:"max,omitempty"`
+	Stations    []stationHeadway `json:"stations"`
+}
+
+type stationHeadway struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	// null if unknown
+	Headway *int `json:"headway"`
+}
+
+func New() *Handler {
+	h := &Handler{
+		lines:    make(map[string]model.Line),
+		stations: make(map[string]data.Line),
+		metrics:  newMetrics(),
+	}
+
+	for _, l := range data.GetLines() {
+		h.stations[l.Name] = l.Line
+	}
+
+	return h
+}
+
+func (h *Handler) Observe(_ context.Context, u *position.Update) {
+	for name, l := range u.Lines {
+		stats := model.SummarizeHeadways(l.Headways())
+		if stats.Count == 0 {
+			h.metrics.Headway.DeleteLabelValues(name, "0")
+			h.metrics.Headway.DeleteLabelValues(name, "0.5")
+			h.metrics.Headway.DeleteLabelValues(name, "0.9")
+			h.metrics.Headway.DeleteLabelValues(name, "1")
+			continue
+		}
+		h.metrics.Headway.WithLabelValues(name, "0").Set(stats.Min)
+		h.metrics.Headway.WithLabelValues(name, "0.5").Set(stats.P50)
+		h.metrics.Headway.WithLabelValues(name, "0.9").Set(stats.P90)
+		h.metrics.Headway.WithLabelValues(name, "1").Set(stats.Max)
+	}
+
+	h.lock.Lock()
+	h.lines = u.Lines // not modified by anyone
+	h.lastUpdated = u.Time
+	h.lock.Unlock()
+}
+
+func (h *Handler) resultForHeadways(name string) (*headwayResult, bool) {
+	stations, ok := h.stations[name]
+	if !ok {
+		return nil, false
+	}
+
+	h.lock.RLock()
+	l := h.lines[name]
+	lastUpdated := h.lastUpdated
+	h.lock.RUnlock()
+
+	out := &headwayResult{
+		Line:        name,
+		LastUpdated: uint64(lastUpdated.UnixNano() / 1000000),
+		Stations:    make([]stationHeadway, len(stations)),
+	}
+
+	if len(l) != len(stations) {
+		// no update yet
+		for i := range stations {
+			out.Stations[i] = stationHeadway{Code: stations[i].Code, Name: stations[i].Name}
+		}
+		return out, true
+	}
+
+	headways := l.Headways()
+	for i := range stations {
+		out.Stations[i] = stationHeadway{Code: stations[i].Code, Name: stations[i].Name}
+		if headways[i] >= 0 {
+			hw := headways[i]
+			out.Stations[i].Headway = &hw
+		}
+	}
+
+	stats := model.SummarizeHeadways(headways)
+	out.Count = stats.Count
+	if stats.Count > 0 {
+		out.Min, out.P50, out.P90, out.Max = &stats.Min, &stats.P50, &stats.P90, &stats.Max
+	}
+
+	return out, true
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	var outEface interface{}
+	found := false
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, Prefix), "/")
+	if len(parts) == 2 && parts[1] == "headways" {
+		outEface, found = h.resultForHeadways(parts[0])
+	}
+
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found: %s", r.URL.Path))
+		return
+	}
+
+	marshal, err := json.Marshal(outEface)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, err = w.Write(marshal)
+	if err != nil {
+		log.Printf("error: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.WriteHeader(code)
+	errstr := fmt.Sprintf("{\"error\":%q}", err.Error())
+	_, err2 := w.Write([]byte(errstr))
+	if err2 != nil {
+		log.Printf("error: double fault in lines handler: %v -> %v", err, err2)
+	}
+}
@@ -0,0 +1,63 @@
+package lines
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/server/handler/position"
+)
+
+func TestHeadways(t *testing.T) {
+	h := New()
+
+	src, ok := data.GetLine("ew1")
+	if !ok {
+		t.Fatal("no ew1")
+	}
+	l := make(model.Line, len(src))
+	for i := range l {
+		l[i] = model.Platform{Next: 1, Subseq: 6, Dest: "Tuas Link"}
+	}
+	l[1] = model.Platform{Next: 2, Subseq: 4, Dest: "Tuas Link"}
+	// missing from the results
+	l[2] = model.Platform{}
+	l[3] = model.Platform{Next: -1, Subseq: -1}
+
+	now := time.Unix(1600000000, 0)
+	h.Observe(context.Background(), &position.Update{Time: now, Lines: map[string]model.Line{"ew1": l}})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Prefix+"ew1/headways", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+
+	var got headwayResult
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Line != "ew1" || got.LastUpdated != 1600000000000 || len(got.Stations) != len(src) {
+		t.Errorf("got %+v", got)
+	}
+	if got.Count != len(src)-2 || got.Min == nil || *got.Min != 2 || *got.Max != 5 {
+		t.Errorf("expected the missing and unknown platforms to be skipped, got %+v", got)
+	}
+	if got.Stations[2].Headway != nil || got.Stations[3].Headway != nil || *got.Stations[1].Headway != 2 {
+		t.Errorf("got stations %+v", got.Stations[:4])
+	}
+
+	for _, path := range []string{"xx1/headways", "ew1", "ew1/other"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Prefix+path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected not found, got %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package lines
+
+import "github.com/prometheus/client_golang/prometheus"
+
+type metrics struct {
+	Headway *prometheus.GaugeVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		Headway: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "traintracker",
+			Subsystem: "line",
+			Name:      "headway_minutes",
+			Help:      "Headway between the next two trains across all platforms of a line",
+		}, []string{"line", "quantile"}),
+	}
+
+	prometheus.MustRegister(m.Headway)
+
+	return m
+}
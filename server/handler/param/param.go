@@ -0,0 +1,15 @@
+// Package param parses query parameters shared by several handlers
+package param
+
+import (
+	"strconv"
+	"time"
+)
+
+// ParseTime accepts unix milliseconds, like every time the API returns, or RFC 3339.
+func ParseTime(s string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,126 @@
+package position
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/recorder"
+)
+
+// a line recorded further than this from ?at= is treated as not recorded
+const maxAtDistance = 5 * time.Minute
+
+var errNoSnapshot = errors.New("nothing was recorded near that time")
+
+// resultsAt serves ?at=, the recorded snapshot nearest to t in the same shape as the live response.
+// Every line is taken from the same tick where possible, so dev_v1 frames are what a board saw.
+func (h *handler) resultsAt(ctx context.Context, t time.Time, format string) (interface{}, error) {
+	if h.recorded == nil {
+		return nil, errNoRecordings
+	}
+
+	nearest := make(map[string]recorder.Row)
+	var tick time.Time
+	for _, l := range data.GetLines() {
+		row, ok, err := h.recorded.Nearest(ctx, l.Name, t)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || absDuration(row.Time.Sub(t)) > maxAtDistance {
+			continue
+		}
+		nearest[l.Name] = row
+		if tick.IsZero() || absDuration(row.Time.Sub(t)) < absDuration(tick.Sub(t)) {
+			tick = row.Time
+		}
+	}
+	if len(nearest) == 0 {
+		return nil, errNoSnapshot
+	}
+
+	positions := make(map[string]model.Position)
+	rows := make(map[string]recorder.Row)
+	for _, l := range data.GetLines() {
+		row, ok := nearest[l.Name]
+		if !ok {
+			continue
+		}
+		if !row.Time.Equal(tick) {
+			// a line can be missing from a tick when its save failed, fall back to its own nearest
+			same, err := h.recorded.QueryRange(ctx, l.Name, tick, tick.Add(time.Millisecond), 1)
+			if err != nil {
+				return nil, err
+			}
+			if len(same) > 0 {
+				row = same[0]
+			}
+		}
+
+		p, err := model.NewPositionFromString(row.Repr)
+		if err != nil || len(p) != len(l.Line)*2-1 {
+			// recorded before the line changed
+			continue
+		}
+		positions[l.Name] = p
+		rows[l.Name] = row
+	}
+	if len(rows) == 0 {
+		return nil, errNoSnapshot
+	}
+
+	if format == "dev_v1" {
+		packMap := make(map[string]model.Position)
+		for _, l := range data.GetLines() {
+			p, ok := positions[l.Name]
+			if !ok {
+				p = make(model.Position, len(l.Line)*2-1)
+			}
+			packMap[l.Name] = p
+		}
+
+		packedHex, err := packDevV1(packMap)
+		if err != nil {
+			return nil, err
+		}
+		return &machineResult{
+			Data:        packedHex,
+			LastUpdated: uint64(tick.UnixNano() / 1000000),
+			Source:      rows[firstLine(rows)].Source,
+		}, nil
+	}
+
+	var out []result
+	for _, l := range data.GetLines() {
+		row, ok := rows[l.Name]
+		if !ok {
+			continue
+		}
+		out = append(out, result{
+			Line:        l.Name,
+			Positions:   positions[l.Name].ToString(),
+			LastUpdated: uint64(row.Time.UnixNano() / 1000000),
+			Source:      row.Source,
+		})
+	}
+	return out, nil
+}
+
+// firstLine returns the first line in line order that is in rows
+func firstLine(rows map[string]recorder.Row) string {
+	for _, l := range data.GetLines() {
+		if _, ok := rows[l.Name]; ok {
+			return l.Name
+		}
+	}
+	return ""
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,143 @@
+package position
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/model"
+)
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string            `json:"type"`
+	Geometry   pointGeometry     `json:"geometry"`
+	Properties featureProperties `json:"properties"`
+}
+
+type pointGeometry struct {
+	Type string `json:"type"`
+	// GeoJSON is longitude first
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type featureProperties struct {
+	Line   string `json:"line"`
+	Colour string `json:"colour"`
+	// Name of the last station of the line, where the train is heading
+	Towards string `json:"towards"`
+	// Segment label of the position, eg "EW4" or "EW4-EW5"
+	Position  string `json:"position"`
+	AtStation bool   `json:"at_station"`
+	// How far along the track to the next station the train is, 0 to 1. Trains without a
+	// known progress are put halfway, at 0.5.
+	Progress    float64 `json:"progress"`
+	LastUpdated uint64  `json:"last_updated"`
+}
+
+// GeoJSON returns a handler serving the live positions as a GeoJSON FeatureCollection of
+// points, one per train. Trains between stations are put as far along the track as their
+// progress, or halfway if it isn't known.
+func (h *handler) GeoJSON() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/geo+json")
+
+		marshal, err := json.Marshal(h.resultForGeoJSON())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			errstr := fmt.Sprintf("{\"error\":%q}", err.Error())
+			_, err2 := w.Write([]byte(errstr))
+			if err2 != nil {
+				log.Printf("error: double fault in position geojson handler: %v -> %v", err, err2)
+			}
+			return
+		}
+		_, err = w.Write(marshal)
+		if err != nil {
+			log.Printf("error: %v", err)
+		}
+	})
+}
+
+func (h *handler) resultForGeoJSON() featureCollection {
+	out := featureCollection{Type: "FeatureCollection", Features: []feature{}}
+
+	for _, l := range data.GetLines() {
+		ent := h.sharedMap[l.Name]
+		if ent == nil {
+			continue
+		}
+
+		ent.lock.RLock()
+		pos := ent.position.Copy()
+		progress := ent.progress.Copy()
+		lastUpdated := uint64(ent.lastUpdated.UnixNano() / 1000000)
+		ent.lock.RUnlock()
+
+		out.Features = append(out.Features, lineFeatures(l, pos, progress, lastUpdated)...)
+	}
+
+	return out
+}
+
+// lineFeatures places each train of the line, skipping any without a known location
+func lineFeatures(l data.LineNameDataPair, pos model.Position, progress model.Progress, lastUpdated uint64) []feature {
+	if len(pos) != len(l.Line)*2-1 {
+		return nil
+	}
+
+	labels := l.Line.SegmentLabels()
+	colour := l.Line.Colour()
+	towards := l.Line[len(l.Line)-1].Name
+
+	var out []feature
+	for i, train := range pos {
+		if !train {
+			continue
+		}
+
+		var at data.LatLng
+		f := 0.0
+		if i%2 == 0 {
+			s := l.Line[i/2]
+			if !s.HasLocation() {
+				continue
+			}
+			at = s.Location()
+		} else {
+			path := data.GetPath(l.Line[i/2], l.Line[i/2+1])
+			if path == nil {
+				continue
+			}
+			f = 0.5
+			if len(progress) == len(pos) && progress[i] != model.UnknownProgress {
+				f = progress[i]
+			}
+			at = data.Along(path, f)
+		}
+
+		out = append(out, feature{
+			Type: "Feature",
+			Geometry: pointGeometry{
+				Type:        "Point",
+				Coordinates: [2]float64{at[1], at[0]},
+			},
+			Properties: featureProperties{
+				Line:        l.Name,
+				Colour:      colour,
+				Towards:     towards,
+				Position:    labels[i],
+				AtStation:   i%2 == 0,
+				Progress:    f,
+				LastUpdated: lastUpdated,
+			},
+		})
+	}
+	return out
+}
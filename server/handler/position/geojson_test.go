@@ -0,0 +1,61 @@
+package position
+
+import (
+	"math"
+	"testing"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/model"
+)
+
+func TestLineFeatures(t *testing.T) {
+	// no tracks are loaded for these, so trains go in a straight line
+	l := data.LineNameDataPair{Name: "te1", Line: data.Line{
+		{Code: "TE1", Name: "Woodlands North", Lat: 1.30, Lng: 103.80},
+		{Code: "TE2", Name: "Woodlands", Lat: 1.30, Lng: 103.84},
+		{Code: "TE3", Name: "Woodlands South", Lat: 1.34, Lng: 103.84},
+	}}
+	pos := model.Position{true, true, false, true, false}
+	progress := model.Progress{0, 0.25, 0, model.UnknownProgress, 0}
+
+	type want struct {
+		position string
+		lng, lat float64
+		progress float64
+	}
+	check := func(got []feature, wants []want) {
+		t.Helper()
+		if len(got) != len(wants) {
+			t.Fatalf("got %d features, want %d", len(got), len(wants))
+		}
+		for i, w := range wants {
+			f := got[i]
+			p := f.Properties
+			if p.Position != w.position || p.Line != "te1" || p.Towards != "Woodlands South" ||
+				p.Colour != "#9D5B25" || p.AtStation != (i == 0) || p.LastUpdated != 1000 {
+				t.Errorf("feature %d: got properties %+v", i, p)
+			}
+			c := f.Geometry.Coordinates
+			if math.Abs(c[0]-w.lng) > 1e-9 || math.Abs(c[1]-w.lat) > 1e-9 || p.Progress != w.progress {
+				t.Errorf("%s: got %v at %v, want %v at [%v %v]", w.position, p.Progress, c, w.progress, w.lng, w.lat)
+			}
+		}
+	}
+
+	check(lineFeatures(l, pos, progress, 1000), []want{
+		{"TE1", 103.80, 1.30, 0},
+		{"TE1-TE2", 103.81, 1.30, 0.25},
+		{"TE2-TE3", 103.84, 1.32, 0.5},
+	})
+
+	// halfway without any progress
+	check(lineFeatures(l, pos, nil, 1000), []want{
+		{"TE1", 103.80, 1.30, 0},
+		{"TE1-TE2", 103.82, 1.30, 0.5},
+		{"TE2-TE3", 103.84, 1.32, 0.5},
+	})
+
+	if f := lineFeatures(l, pos[:3], nil, 1000); f != nil {
+		t.Errorf("expected nothing for a position of the wrong length, got %+v", f)
+	}
+}
@@ -1,6 +1,10 @@
 package position
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 type metrics struct {
 	Requests      prometheus.Counter
@@ -18,7 +22,21 @@ func (m *metrics) valid() bool {
 		m.BgLastUpdated != nil
 }
 
+var (
+	registerOnce sync.Once
+	registered   *metrics
+)
+
+// newMetrics returns the metrics, registered the first time. Registration is process wide, so
+// every handler shares them.
 func newMetrics() *metrics {
+	registerOnce.Do(func() {
+		registered = registerMetrics()
+	})
+	return registered
+}
+
+func registerMetrics() *metrics {
 	m := &metrics{
 		Requests: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: "traintracker",
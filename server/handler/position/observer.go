@@ -0,0 +1,48 @@
+package position
+
+import (
+	"context"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+// Update is everything the handler worked out in one tick of the update loop.
+// Observers must treat it as read-only, it is shared between all of them.
+type Update struct {
+	Time time.Time
+	// Per-platform arrivals, keyed by line name
+	Lines map[string]model.Line
+	// Inferred positions, keyed by line name
+	Positions map[string]model.Position
+	// Hex-encoded packed frames for the dev v1 board
+	DevV1 []string
+	// Arrivals from the provider, keyed by station name
+	Results map[string]smrt.Result
+}
+
+// Observer is notified after every successful update.
+// Observe is called from the update goroutine, so it should not block for longer than the
+// update interval. ctx has its own deadline of one update interval, however long the update
+// itself took, and is cancelled when the handler stops.
+type Observer interface {
+	Observe(ctx context.Context, u *Update)
+}
+
+// ObserverFunc adapts a plain function to an Observer.
+type ObserverFunc func(ctx context.Context, u *Update)
+
+func (f ObserverFunc) Observe(ctx context.Context, u *Update) {
+	f(ctx, u)
+}
+
+// notify calls every observer with the update. The tick's ctx isn't used, a slow scrape would
+// leave them too little of it.
+func (h *handler) notify(u *Update) {
+	ctx, cancel := context.WithTimeout(h.ctx, h.interval)
+	defer cancel()
+	for _, o := range h.observers {
+		o.Observe(ctx, u)
+	}
+}
@@ -3,6 +3,7 @@ package position
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,7 +11,10 @@ import (
 	"time"
 
 	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/lta"
 	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/provider"
+	"go.lepak.sg/mrtracker-backend/server/handler/param"
 	"go.lepak.sg/mrtracker-backend/smrt"
 )
 
@@ -21,7 +25,15 @@ const (
 
 const (
 	UpdateLive = iota
+	// UpdateRecorded serves the typical day built from recordings instead of live data
 	UpdateRecorded
+	// UpdateReplay plays back recordings from a time range as if they were live
+	UpdateReplay
+)
+
+var (
+	errNoTypical    = errors.New("no recordings to build a typical day from")
+	errNoRecordings = errors.New("no recordings")
 )
 
 type handler struct {
@@ -36,9 +48,20 @@ type handler struct {
 	wg       sync.WaitGroup
 	tick     *time.Ticker
 	interval time.Duration
+	strategy int
+
+	provider provider.Provider
+
+	observers []Observer
+
+	// nil if there is nowhere to get the typical day from
+	typical *typicalCache
 
-	numWorkers int
-	maxTries   int
+	recorded Recorded
+	replay   ReplayConfig
+
+	// nil if there are no official alerts
+	serviceAlerts ServiceAlerts
 
 	metrics *metrics
 }
@@ -47,15 +70,30 @@ type entry struct {
 	// rlocked by ServeHTTP, locked by update
 	lock        sync.RWMutex
 	position    model.Position
+	progress    model.Progress
+	occupancy   model.Occupancy
 	data        []string
 	lastUpdated time.Time
+	source      string
 }
 
 type result struct {
-	Line        string `json:"line"`
-	Positions   string `json:"positions"`
-	LastUpdated uint64 `json:"last_updated"`
-	Source      string `json:"source,omitempty"`
+	Line      string `json:"line"`
+	Positions string `json:"positions"`
+	// Probability of a train at each position, only for the typical day
+	Probabilities []float64 `json:"probabilities,omitempty"`
+	// How far along the track between stations each train is, 0 to 1 or -1 if it isn't known,
+	// only for live positions
+	Progress    []float64 `json:"progress,omitempty"`
+	LastUpdated uint64    `json:"last_updated"`
+	Source      string    `json:"source,omitempty"`
+	// Official alerts for the line, only for live positions
+	ServiceAlerts []lta.LineAlert `json:"service_alerts,omitempty"`
+}
+
+// ServiceAlerts are the official alerts served with live positions, normally an *lta.Cache
+type ServiceAlerts interface {
+	Get(ctx context.Context) (*lta.ServiceAlerts, map[string][]lta.LineAlert, time.Time)
 }
 
 type machineResult struct {
@@ -68,8 +106,22 @@ type NewParam struct {
 	Ctx            context.Context
 	UpdateInterval time.Duration
 	Strategy       int
-	NumWorkers     int
-	MaxTries       int
+	// Where live arrivals come from. Defaults to the smrt API with NumWorkers and MaxTries.
+	Provider   provider.Provider
+	NumWorkers int
+	MaxTries   int
+	// Observers are notified after every successful live update, in order
+	Observers []Observer
+	// Typical enables ?source=typical, and is required for UpdateRecorded
+	Typical Typical
+	// Positions with a probability at least this are shown as having a train on the typical day.
+	// Defaults to 0.5.
+	TypicalThreshold float64
+	// Recorded enables ?at=, and is required for UpdateReplay along with Replay
+	Recorded Recorded
+	Replay   *ReplayConfig
+	// ServiceAlerts are added to live positions if not nil
+	ServiceAlerts ServiceAlerts
 }
 
 func New(p NewParam) (*handler, error) {
@@ -85,30 +137,62 @@ func New(p NewParam) (*handler, error) {
 	if p.NumWorkers < 0 {
 		p.NumWorkers = 0
 	}
+	if p.TypicalThreshold <= 0 {
+		p.TypicalThreshold = defaultTypicalThreshold
+	}
+	if p.Provider == nil {
+		p.Provider = smrt.Provider{NumWorkers: p.NumWorkers, MaxTries: p.MaxTries}
+	}
 
 	h := &handler{
-		sharedMap:  make(map[string]*entry),
-		tick:       time.NewTicker(p.UpdateInterval),
-		interval:   p.UpdateInterval,
-		metrics:    newMetrics(),
-		numWorkers: p.NumWorkers,
-		maxTries:   p.MaxTries,
+		sharedMap: make(map[string]*entry),
+		tick:      time.NewTicker(p.UpdateInterval),
+		interval:  p.UpdateInterval,
+		strategy:  p.Strategy,
+		metrics:   newMetrics(),
+		provider:  p.Provider,
+		observers: p.Observers,
 	}
 	h.ctx, h.cancel = context.WithCancel(p.Ctx)
 
+	if p.Typical != nil {
+		h.typical = &typicalCache{source: p.Typical, threshold: p.TypicalThreshold}
+	}
+	h.recorded = p.Recorded
+	h.serviceAlerts = p.ServiceAlerts
+
 	for _, l := range data.GetLines() {
 		h.sharedMap[l.Name] = &entry{}
 	}
 
 	h.sharedMap["dev_v1"] = &entry{}
 
-	h.wg.Add(1)
 	switch p.Strategy {
 	case UpdateLive:
+		h.wg.Add(1)
 		go h.update()
 	case UpdateRecorded:
-		panic("not implemented")
+		if h.typical == nil {
+			h.cancel()
+			return nil, errNoTypical
+		}
+		h.wg.Add(1)
+		go h.updateTypical()
+	case UpdateReplay:
+		if p.Recorded == nil || p.Replay == nil {
+			h.cancel()
+			return nil, errors.New("replay needs recordings and a range")
+		}
+		h.replay = *p.Replay
+		err := h.replay.validate()
+		if err != nil {
+			h.cancel()
+			return nil, err
+		}
+		h.wg.Add(1)
+		go h.updateReplay()
 	default:
+		h.cancel()
 		return nil, fmt.Errorf("unrecognized update strategy: %d", p.Strategy)
 	}
 
@@ -148,53 +232,72 @@ func (h *handler) update() {
 				}
 			}()
 
-			results, tries, err := smrt.GetN(ctx, h.numWorkers, h.maxTries, names...)
+			results, tries, err := h.provider.Arrivals(ctx, names)
 			if err != nil {
-				log.Printf("error: smrt scrape failed: %v", err)
+				log.Printf("error: scrape failed: %v", err)
 				return
 			}
 			log.Printf("tries: %d", tries)
 			h.metrics.BgRequests.Add(float64(tries))
 
+			now := time.Now()
+			lineMap := make(map[string]model.Line)
 			workingMap := make(map[string]model.Position)
+			progressMap := make(map[string]model.Progress)
 			for _, l := range data.GetLines() {
-				workingMap[l.Name] = smrt.ToModel(results, l.Line).ToPosition()
+				lineMap[l.Name] = smrt.ToModel(results, l.Line)
+				workingMap[l.Name], progressMap[l.Name] = lineMap[l.Name].Estimate(l.Line)
 			}
 
 			for _, l := range data.GetLines() {
 				ent := h.sharedMap[l.Name]
 				ent.lock.Lock()
 				ent.position = workingMap[l.Name].Copy()
-				ent.lastUpdated = time.Now()
+				ent.progress = progressMap[l.Name]
+				ent.lastUpdated = now
 				ent.lock.Unlock()
 			}
 
-			for k, v := range workingMap {
-				workingMap[k] = v[:len(v)-1]
-			}
-
-			packed, err := model.PackBoardV1(workingMap)
+			packedHex, err := packDevV1(workingMap)
 			if err != nil {
-				log.Printf("error: packing for dev v1: %v", err)
+				log.Printf("error: %v", err)
 				return
 			}
 
-			packedHex := make([]string, len(packed))
-			for i := range packedHex {
-				packedHex[i] = fmt.Sprintf("%x", packed[i])
-			}
-
 			ent := h.sharedMap["dev_v1"]
 			ent.lock.Lock()
 			ent.data = packedHex // aliasing is ok, we are not retaining packedHex
-			ent.lastUpdated = time.Now()
+			ent.lastUpdated = now
 			ent.lock.Unlock()
 
 			h.metrics.BgLastUpdated.SetToCurrentTime()
+
+			u := &Update{
+				Time:      now,
+				Lines:     lineMap,
+				Positions: workingMap,
+				DevV1:     packedHex,
+				Results:   results,
+			}
+			h.notify(u)
 		}()
 	}
 }
 
+// packDevV1 packs positions into hex-encoded frames for the dev v1 board
+func packDevV1(positions map[string]model.Position) ([]string, error) {
+	packed, err := model.PackBoardV1(positions)
+	if err != nil {
+		return nil, fmt.Errorf("packing for dev v1: %w", err)
+	}
+
+	packedHex := make([]string, len(packed))
+	for i := range packedHex {
+		packedHex[i] = fmt.Sprintf("%x", packed[i])
+	}
+	return packedHex, nil
+}
+
 func (h *handler) Stop() {
 	h.cancel()
 	h.wg.Wait()
@@ -213,14 +316,21 @@ func (h *handler) resultForDevV1() *machineResult {
 	ent.lock.RLock()
 	out.Data = ent.data
 	out.LastUpdated = uint64(ent.lastUpdated.UnixNano() / 1000000)
+	out.Source = ent.source
 	ent.lock.RUnlock()
 
 	return out
 }
 
-func (h *handler) resultForDefault() []result {
+func (h *handler) resultForDefault(ctx context.Context) []result {
 	var out []result
 
+	var serviceAlerts map[string][]lta.LineAlert
+	// today's alerts don't belong to a replayed day
+	if h.serviceAlerts != nil && h.strategy != UpdateReplay {
+		_, serviceAlerts, _ = h.serviceAlerts.Get(ctx)
+	}
+
 	for _, l := range data.GetLines() {
 		r := result{
 			Line: l.Name,
@@ -233,8 +343,12 @@ func (h *handler) resultForDefault() []result {
 
 		ent.lock.RLock()
 		r.Positions = ent.position.ToString()
+		r.Probabilities = ent.occupancy
+		r.Progress = ent.progress
 		r.LastUpdated = uint64(ent.lastUpdated.UnixNano() / 1000000)
+		r.Source = ent.source
 		ent.lock.RUnlock()
+		r.ServiceAlerts = serviceAlerts[l.Name]
 		out = append(out, r)
 	}
 
@@ -257,16 +371,47 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", "application/json")
 
 	format := r.URL.Query().Get("format")
-	switch format {
-	case "dev_v1":
-		outEface = h.resultForDevV1()
+	source := r.URL.Query().Get("source")
+	at := r.URL.Query().Get("at")
+	status := http.StatusInternalServerError
+	switch {
+	case at != "" && source != "":
+		err = errors.New("at can't be used with source")
+		status = http.StatusBadRequest
+	case at != "":
+		var t time.Time
+		t, err = param.ParseTime(at)
+		if err != nil {
+			status = http.StatusBadRequest
+			break
+		}
+		outEface, err = h.resultsAt(r.Context(), t, format)
+		if err == errNoRecordings || err == errNoSnapshot {
+			status = http.StatusNotFound
+		}
+	case source == "" || source == "live":
+		switch format {
+		case "dev_v1":
+			outEface = h.resultForDevV1()
+		default:
+			outEface = h.resultForDefault(r.Context())
+		}
+	case source == SourceTypical:
+		outEface, err = h.resultsForTypical(r.Context(), format)
+		if err == errNoTypical {
+			status = http.StatusNotFound
+		}
 	default:
-		outEface = h.resultForDefault()
+		err = errors.New("unknown source")
+		status = http.StatusBadRequest
 	}
 
-	marshal, err := json.Marshal(outEface)
+	var marshal []byte
+	if err == nil {
+		marshal, err = json.Marshal(outEface)
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(status)
 		errstr := fmt.Sprintf("{\"error\":%q}", err.Error())
 		_, err2 := w.Write([]byte(errstr))
 		if err2 != nil {
@@ -0,0 +1,85 @@
+package position
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/provider"
+	"go.lepak.sg/mrtracker-backend/sbs"
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+// noTrains is an smrt stand in that has nothing for any station
+type noTrains struct{}
+
+func (noTrains) Arrivals(_ context.Context, _ []string) (map[string]smrt.Result, int64, error) {
+	return map[string]smrt.Result{}, 1, nil
+}
+
+func TestLiveRoute(t *testing.T) {
+	// a train at HarbourFront, heading for Punggol
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"stations": {"HarbourFront": [
+			{"platform": "HBF_A", "trains": [{"destination": "Punggol", "minutes": 0}]}
+		]}}`))
+	}))
+	defer srv.Close()
+
+	updated := make(chan struct{}, 1)
+	h, err := New(NewParam{
+		UpdateInterval: time.Hour,
+		Strategy:       UpdateLive,
+		Provider: provider.Route{
+			Default:  noTrains{},
+			Prefixes: map[string]provider.Provider{"NE": &sbs.Provider{URL: srv.URL}},
+		},
+		Observers: []Observer{ObserverFunc(func(ctx context.Context, u *Update) {
+			select {
+			case updated <- struct{}{}:
+			default:
+			}
+		})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-updated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update")
+	}
+	h.Stop()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v1/position", nil))
+	var results []result
+	err = json.Unmarshal(w.Body.Bytes(), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// HarbourFront's one platform ends ne2 as well as starting ne1
+	atHarbourFront := map[string]func(string, string) bool{
+		"ne1": strings.HasPrefix,
+		"ne2": strings.HasSuffix,
+	}
+	for _, r := range results {
+		at, ok := atHarbourFront[r.Line]
+		if ok {
+			delete(atHarbourFront, r.Line)
+			if !at(r.Positions, "*") || strings.Count(r.Positions, "*") != 1 {
+				t.Errorf("%s: expected a train at HarbourFront only, got %s", r.Line, r.Positions)
+			}
+		} else if strings.Contains(r.Positions, "*") {
+			t.Errorf("%s: expected no trains, got %s", r.Line, r.Positions)
+		}
+	}
+	for name := range atHarbourFront {
+		t.Errorf("%s isn't in the line data", name)
+	}
+}
@@ -0,0 +1,206 @@
+package position
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/recorder"
+)
+
+const (
+	SourceReplay = "replay"
+
+	// recorded time fetched from the store at once
+	replayWindow = 15 * time.Minute
+	replayLimit  = 1000
+)
+
+// Recorded is where replays and ?at= come from, normally a recorder.Store
+type Recorded interface {
+	QueryRange(ctx context.Context, name string, from, to time.Time, limit int) ([]recorder.Row, error)
+	Nearest(ctx context.Context, name string, t time.Time) (recorder.Row, bool, error)
+}
+
+// ReplayConfig is for UpdateReplay
+type ReplayConfig struct {
+	From time.Time
+	To   time.Time
+	// 2 plays back twice as fast as recorded. Defaults to 1.
+	Speed float64
+	// Start again from the beginning at the end, instead of stopping on the last snapshot
+	Loop bool
+}
+
+func (c *ReplayConfig) validate() error {
+	if c.Speed == 0 {
+		c.Speed = 1
+	}
+	if c.Speed < 0 {
+		return errors.New("replay speed can't be negative")
+	}
+	if !c.From.Before(c.To) {
+		return errors.New("replay range is empty")
+	}
+	return nil
+}
+
+// replayFrame is every line recorded at one instant
+type replayFrame struct {
+	time      time.Time
+	positions map[string]model.Position
+	lines     map[string]model.Line
+}
+
+// updateReplay is the update loop for UpdateReplay. Recorded snapshots are put into the shared
+// entries on the same schedule as they were recorded, sped up, and observers see them as if
+// they were live.
+func (h *handler) updateReplay() {
+	defer h.wg.Done()
+
+	for {
+		err := h.replayOnce()
+		if h.ctx.Err() != nil {
+			log.Print("exiting update loop")
+			return
+		}
+		if err != nil {
+			log.Printf("error: replay: %v", err)
+			h.metrics.BgErrors.Inc()
+		}
+		if !h.replay.Loop {
+			log.Print("replay finished")
+			return
+		}
+	}
+}
+
+// replayOnce plays the whole range once
+func (h *handler) replayOnce() error {
+	c := h.replay
+	start := time.Now()
+
+	// keep showing lines that are missing from a frame, like the live loop does when a line fails
+	current := make(map[string]model.Position)
+
+	for window := c.From; window.Before(c.To); window = window.Add(replayWindow) {
+		end := window.Add(replayWindow)
+		if end.After(c.To) {
+			end = c.To
+		}
+
+		frames, err := h.loadFrames(window, end)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range frames {
+			due := start.Add(time.Duration(float64(f.time.Sub(c.From)) / c.Speed))
+			timer := time.NewTimer(time.Until(due))
+			select {
+			case <-h.ctx.Done():
+				timer.Stop()
+				return h.ctx.Err()
+			case <-timer.C:
+			}
+
+			h.showFrame(f, current)
+		}
+	}
+
+	return nil
+}
+
+// loadFrames reads every line between from and to, and groups the rows by time
+func (h *handler) loadFrames(from, to time.Time) ([]*replayFrame, error) {
+	ctx, cancel := context.WithTimeout(h.ctx, h.interval)
+	defer cancel()
+
+	byTime := make(map[int64]*replayFrame)
+	for _, l := range data.GetLines() {
+		rows, err := h.recorded.QueryRange(ctx, l.Name, from, to, replayLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			p, err := model.NewPositionFromString(row.Repr)
+			if err != nil || len(p) != len(l.Line)*2-1 {
+				continue
+			}
+
+			key := row.Time.UnixNano()
+			f, ok := byTime[key]
+			if !ok {
+				f = &replayFrame{
+					time:      row.Time,
+					positions: make(map[string]model.Position),
+					lines:     make(map[string]model.Line),
+				}
+				byTime[key] = f
+			}
+
+			f.positions[l.Name] = p
+			if arr, err := recorder.DecodeArrivals(row.Arrivals); err == nil && len(arr) == len(l.Line) {
+				f.lines[l.Name] = arr
+			}
+		}
+	}
+
+	frames := make([]*replayFrame, 0, len(byTime))
+	for _, f := range byTime {
+		frames = append(frames, f)
+	}
+	sort.Slice(frames, func(i, j int) bool { return frames[i].time.Before(frames[j].time) })
+	return frames, nil
+}
+
+// showFrame puts a frame into the shared entries, as the live update loop would
+func (h *handler) showFrame(f *replayFrame, current map[string]model.Position) {
+	for name, p := range f.positions {
+		current[name] = p
+		ent := h.sharedMap[name]
+		ent.lock.Lock()
+		ent.position = p
+		ent.lastUpdated = f.time
+		ent.source = SourceReplay
+		ent.lock.Unlock()
+	}
+
+	packMap := make(map[string]model.Position)
+	for _, l := range data.GetLines() {
+		p, ok := current[l.Name]
+		if !ok {
+			p = make(model.Position, len(l.Line)*2-1)
+		}
+		packMap[l.Name] = p
+	}
+
+	packedHex, err := packDevV1(packMap)
+	if err != nil {
+		log.Printf("error: %v", err)
+		h.metrics.BgErrors.Inc()
+		return
+	}
+
+	ent := h.sharedMap["dev_v1"]
+	ent.lock.Lock()
+	ent.data = packedHex
+	ent.lastUpdated = f.time
+	ent.source = SourceReplay
+	ent.lock.Unlock()
+
+	h.metrics.BgLastUpdated.SetToCurrentTime()
+
+	u := &Update{
+		Time:      f.time,
+		Lines:     f.lines,
+		Positions: packMap,
+		DevV1:     packedHex,
+	}
+	h.notify(u)
+}
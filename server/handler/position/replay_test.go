@@ -0,0 +1,167 @@
+package position
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/recorder"
+)
+
+type fakeRecorded map[string][]recorder.Row
+
+func (f fakeRecorded) QueryRange(ctx context.Context, name string, from, to time.Time, limit int) ([]recorder.Row, error) {
+	var out []recorder.Row
+	for _, r := range f[name] {
+		if !r.Time.Before(from) && r.Time.Before(to) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (f fakeRecorded) Nearest(ctx context.Context, name string, t time.Time) (recorder.Row, bool, error) {
+	var best recorder.Row
+	found := false
+	for _, r := range f[name] {
+		if !found || absDuration(r.Time.Sub(t)) < absDuration(best.Time.Sub(t)) {
+			best = r
+			found = true
+		}
+	}
+	return best, found, nil
+}
+
+// recordTwoFrames records every line 30s apart, with a train at the first and then the second
+// position, and returns the last frame of ew1
+func recordTwoFrames(start time.Time) (fakeRecorded, string) {
+	rec := make(fakeRecorded)
+	var last string
+	for _, l := range data.GetLines() {
+		for i := 0; i < 2; i++ {
+			repr := strings.Repeat("_", len(l.Line)*2-1)
+			repr = repr[:i] + "*" + repr[i+1:]
+			rec[l.Name] = append(rec[l.Name], recorder.Row{
+				Time: start.Add(time.Duration(i) * 30 * time.Second),
+				Name: l.Name,
+				Repr: repr,
+			})
+			if l.Name == "ew1" {
+				last = repr
+			}
+		}
+	}
+	return rec, last
+}
+
+func TestReplay(t *testing.T) {
+	start := time.Date(2021, 9, 6, 8, 0, 0, 0, time.Local)
+	rec, last := recordTwoFrames(start)
+
+	var updates int
+	h, err := New(NewParam{
+		Strategy: UpdateReplay,
+		Recorded: rec,
+		Replay: &ReplayConfig{
+			From:  start,
+			To:    start.Add(time.Minute),
+			Speed: 1000,
+		},
+		Observers: []Observer{ObserverFunc(func(ctx context.Context, u *Update) {
+			updates++
+		})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the replay stops by itself after the last frame
+	h.wg.Wait()
+	h.Stop()
+
+	if updates != 2 {
+		t.Errorf("expected 2 updates, got %d", updates)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v1/position", nil))
+	var results []result
+	err = json.Unmarshal(w.Body.Bytes(), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Line != "ew1" {
+			continue
+		}
+		if r.Positions != last || r.Source != SourceReplay {
+			t.Errorf("expected the last frame, got %+v", r)
+		}
+		if r.LastUpdated != uint64(start.Add(30*time.Second).UnixNano()/1000000) {
+			t.Errorf("expected the recorded time, got %d", r.LastUpdated)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v1/position?format=dev_v1", nil))
+	var mr machineResult
+	err = json.Unmarshal(w.Body.Bytes(), &mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mr.Data) == 0 || mr.Source != SourceReplay {
+		t.Errorf("expected dev_v1 frames, got %+v", mr)
+	}
+
+}
+
+func TestAt(t *testing.T) {
+	start := time.Date(2021, 9, 6, 8, 0, 0, 0, time.Local)
+	rec, _ := recordTwoFrames(start)
+
+	// only the recordings are needed, New would start updating in the background
+	h := &handler{
+		recorded: rec,
+		metrics: &metrics{
+			Requests: prometheus.NewCounter(prometheus.CounterOpts{Name: "requests"}),
+			Errors:   prometheus.NewCounter(prometheus.CounterOpts{Name: "errors"}),
+			Latency:  prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency"}),
+		},
+	}
+
+	// time travel to the first frame
+	at := start.Add(10 * time.Second)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v1/position?at="+at.Format(time.RFC3339), nil))
+	var results []result
+	err := json.Unmarshal(w.Body.Bytes(), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(data.GetLines()) || results[0].LastUpdated != uint64(start.UnixNano()/1000000) {
+		t.Errorf("expected the first frame, got %+v", results)
+	}
+
+	// and in unix ms
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/v1/position?format=dev_v1&at=%d", at.UnixNano()/1000000), nil))
+	var mr machineResult
+	err = json.Unmarshal(w.Body.Bytes(), &mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mr.LastUpdated != uint64(start.UnixNano()/1000000) {
+		t.Errorf("expected the first frame, got %+v", mr)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/v1/position?at=0", nil))
+	if w.Code != 404 {
+		t.Errorf("expected nothing recorded in 1970, got %d", w.Code)
+	}
+}
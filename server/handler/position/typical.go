@@ -0,0 +1,190 @@
+package position
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/recorder"
+)
+
+const (
+	SourceTypical = "typical"
+
+	defaultTypicalThreshold = 0.5
+	// Slots are minutes wide, there's no point asking the store more often than this
+	typicalTTL = time.Minute
+)
+
+// Typical is where the typical day comes from, normally a recorder.Store
+type Typical interface {
+	Typical(ctx context.Context, name string, dayOfWeek, secondsOfDay int) (recorder.Aggregate, error)
+}
+
+// typicalSnapshot is the typical occupancy of every line at one time of the week
+type typicalSnapshot struct {
+	time      time.Time
+	occupancy map[string]model.Occupancy
+	// occupancy put through the threshold
+	positions map[string]model.Position
+	devV1     []string
+}
+
+type typicalCache struct {
+	source    Typical
+	threshold float64
+
+	// only guards snap, snapshots are built outside it so a slow store doesn't hold up readers
+	lock sync.Mutex
+	snap *typicalSnapshot
+}
+
+// get returns the typical snapshot for now, reusing the last one if it is recent enough
+func (c *typicalCache) get(ctx context.Context, now time.Time) (*typicalSnapshot, error) {
+	c.lock.Lock()
+	cached := c.snap
+	c.lock.Unlock()
+
+	if cached != nil && now.Sub(cached.time) >= 0 && now.Sub(cached.time) < typicalTTL {
+		return cached, nil
+	}
+
+	snap, err := c.build(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	// another caller may have built a later one in the meantime
+	if c.snap == nil || snap.time.After(c.snap.time) {
+		c.snap = snap
+	}
+	c.lock.Unlock()
+
+	return snap, nil
+}
+
+// build queries the typical occupancy of every line at now
+func (c *typicalCache) build(ctx context.Context, now time.Time) (*typicalSnapshot, error) {
+	dayOfWeek := int(now.Weekday())
+	secondsOfDay := now.Hour()*3600 + now.Minute()*60 + now.Second()
+
+	snap := &typicalSnapshot{
+		time:      now,
+		occupancy: make(map[string]model.Occupancy),
+		positions: make(map[string]model.Position),
+	}
+	for _, l := range data.GetLines() {
+		agg, err := c.source.Typical(ctx, l.Name, dayOfWeek, secondsOfDay)
+		if err != nil {
+			return nil, fmt.Errorf("typical %s: %w", l.Name, err)
+		}
+
+		occ := agg.Occupancy()
+		if len(occ) != len(l.Line)*2-1 {
+			// never recorded, or recorded before the line changed
+			occ = make(model.Occupancy, len(l.Line)*2-1)
+		}
+		snap.occupancy[l.Name] = occ
+		snap.positions[l.Name] = occ.ToPosition(c.threshold)
+	}
+
+	var err error
+	snap.devV1, err = packDevV1(snap.positions)
+	if err != nil {
+		return nil, err
+	}
+
+	return snap, nil
+}
+
+// updateTypical is the update loop for UpdateRecorded. It serves the typical day in place of
+// live data, so observers are not notified.
+func (h *handler) updateTypical() {
+	defer h.wg.Done()
+	running := true
+	for running {
+		func() {
+			startTime := time.Now()
+			ctx, cancel := context.WithTimeout(h.ctx, h.interval)
+			var err error
+
+			defer func() {
+				cancel()
+				h.metrics.BgLatency.Observe(time.Since(startTime).Seconds())
+				if err != nil {
+					h.metrics.BgErrors.Inc()
+				}
+
+				select {
+				case <-h.ctx.Done():
+					log.Print("exiting update loop")
+					running = false
+				case <-h.tick.C:
+				}
+			}()
+
+			snap, err := h.typical.get(ctx, time.Now())
+			if err != nil {
+				log.Printf("error: typical day: %v", err)
+				return
+			}
+
+			for _, l := range data.GetLines() {
+				ent := h.sharedMap[l.Name]
+				ent.lock.Lock()
+				ent.position = snap.positions[l.Name]
+				ent.occupancy = snap.occupancy[l.Name]
+				ent.lastUpdated = snap.time
+				ent.source = SourceTypical
+				ent.lock.Unlock()
+			}
+
+			ent := h.sharedMap["dev_v1"]
+			ent.lock.Lock()
+			ent.data = snap.devV1
+			ent.lastUpdated = snap.time
+			ent.source = SourceTypical
+			ent.lock.Unlock()
+
+			h.metrics.BgLastUpdated.SetToCurrentTime()
+		}()
+	}
+}
+
+// resultsForTypical serves ?source=typical, whatever the update strategy is
+func (h *handler) resultsForTypical(ctx context.Context, format string) (interface{}, error) {
+	if h.typical == nil {
+		return nil, errNoTypical
+	}
+
+	snap, err := h.typical.get(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	lastUpdated := uint64(snap.time.UnixNano() / 1000000)
+
+	if format == "dev_v1" {
+		return &machineResult{
+			Data:        snap.devV1,
+			LastUpdated: lastUpdated,
+			Source:      SourceTypical,
+		}, nil
+	}
+
+	var out []result
+	for _, l := range data.GetLines() {
+		out = append(out, result{
+			Line:          l.Name,
+			Positions:     snap.positions[l.Name].ToString(),
+			Probabilities: snap.occupancy[l.Name],
+			LastUpdated:   lastUpdated,
+			Source:        SourceTypical,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,86 @@
+package stations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"go.lepak.sg/mrtracker-backend/data"
+)
+
+// Prefix is where the handler should be mounted. Routes below it:
+//
+//	<station>
+//
+// where station is a code (EW4), three letter code (TNM), platform ID (TNM_B) or name.
+const Prefix = "/v1/stations/"
+
+// Handler looks stations up in the loaded line data
+type Handler struct{}
+
+type result struct {
+	Name        string   `json:"name"`
+	Code3       string   `json:"code3"`
+	Codes       []string `json:"codes"`
+	Interchange bool     `json:"interchange"`
+	Lines       []stop   `json:"lines"`
+}
+
+type stop struct {
+	Line     string `json:"line"`
+	Code     string `json:"code"`
+	Platform string `json:"platform"`
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	q := strings.TrimPrefix(r.URL.Path, Prefix)
+	node, err := data.GetIndex().Lookup(q)
+	var ambiguous *data.AmbiguousError
+	switch {
+	case errors.As(err, &ambiguous):
+		writeError(w, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusNotFound, fmt.Errorf("%v: %s", err, q))
+		return
+	}
+
+	out := result{
+		Name:        node.Name,
+		Code3:       node.Code3,
+		Codes:       node.Codes,
+		Interchange: node.Interchange(),
+	}
+	for _, s := range node.Stops {
+		out.Lines = append(out.Lines, stop{
+			Line:     s.Line,
+			Code:     s.Code,
+			Platform: s.AsStation().PlatformID(),
+		})
+	}
+
+	marshal, err := json.Marshal(out)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, err = w.Write(marshal)
+	if err != nil {
+		log.Printf("error: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.WriteHeader(code)
+	errstr := fmt.Sprintf("{\"error\":%q}", err.Error())
+	_, err2 := w.Write([]byte(errstr))
+	if err2 != nil {
+		log.Printf("error: double fault in stations handler: %v -> %v", err, err2)
+	}
+}
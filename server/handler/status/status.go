@@ -1,20 +1,37 @@
 package status
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"os"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/lta"
 )
 
 const (
 	envGitRev = "GIT_REV"
 )
 
-type Handler struct{}
+type Handler struct {
+	// If not nil, the official service status is included
+	ServiceAlerts ServiceAlerts
+}
+
+// ServiceAlerts is normally an *lta.Cache
+type ServiceAlerts interface {
+	Get(ctx context.Context) (*lta.ServiceAlerts, map[string][]lta.LineAlert, time.Time)
+}
 
 type result struct {
 	Version string `json:"version"`
+	// "normal" or "disrupted", empty if unknown
+	Service         string                     `json:"service,omitempty"`
+	ServiceAlerts   map[string][]lta.LineAlert `json:"service_alerts,omitempty"`
+	ServiceMessages []string                   `json:"service_messages,omitempty"`
+	ServiceUpdated  uint64                     `json:"service_updated,omitempty"`
 }
 
 func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -24,6 +41,21 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Version: os.Getenv(envGitRev),
 	}
 
+	if h.ServiceAlerts != nil {
+		alerts, lines, fetchedAt := h.ServiceAlerts.Get(r.Context())
+		if alerts != nil {
+			res.Service = "normal"
+			if alerts.Status == lta.StatusDisrupted {
+				res.Service = "disrupted"
+			}
+			res.ServiceAlerts = lines
+			for _, m := range alerts.Message {
+				res.ServiceMessages = append(res.ServiceMessages, m.Content)
+			}
+			res.ServiceUpdated = uint64(fetchedAt.UnixNano() / 1000000)
+		}
+	}
+
 	b, err := json.Marshal(res)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
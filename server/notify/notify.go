@@ -0,0 +1,325 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/server/handler/position"
+)
+
+const (
+	defaultNoTrainTicks = 4
+	defaultMaxGap       = 15
+	defaultCooldown     = 15 * time.Minute
+	defaultTimeout      = 10 * time.Second
+	maxTries            = 3
+	queueSize           = 64
+
+	// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of the request body
+	SignatureHeader = "X-Mrtracker-Signature"
+)
+
+const (
+	KindLineDown = "line_down"
+	KindNoTrains = "no_trains"
+	KindLongGap  = "long_gap"
+)
+
+const (
+	EventTriggered = "triggered"
+	EventResolved  = "resolved"
+)
+
+type Config struct {
+	// Every event is POSTed to all of these
+	URLs []string
+	// Key for the request signature. If empty, requests are not signed
+	Secret string
+	// Ticks with no trains on a line before it is reported
+	NoTrainTicks int
+	// Arrival time in minutes at any platform above which a gap is reported
+	MaxGap int
+	// Minimum time between two triggered events for the same line and kind,
+	// so a flapping line doesn't spam the receivers
+	Cooldown time.Duration
+	// Defaults to a client with a short timeout
+	Client *http.Client
+}
+
+// Event is the body of every webhook request. Times are unix milliseconds like the rest of the API.
+type Event struct {
+	Event      string `json:"event"`
+	Kind       string `json:"kind"`
+	Line       string `json:"line"`
+	Message    string `json:"message"`
+	StartedAt  uint64 `json:"started_at"`
+	ResolvedAt uint64 `json:"resolved_at,omitempty"`
+	SentAt     uint64 `json:"sent_at"`
+}
+
+type key struct {
+	line string
+	kind string
+}
+
+type incident struct {
+	// consecutive ticks the condition has held
+	ticks int
+	open  bool
+	since time.Time
+	// whether the triggered event was sent, and so whether to send a resolved event
+	notified      bool
+	lastTriggered time.Time
+}
+
+// Notifier watches every update for anomalies and sends webhooks when they start and end.
+// Sending happens on a separate goroutine so a slow receiver doesn't hold up the update loop.
+type Notifier struct {
+	c Config
+
+	// only touched by Observe
+	incidents map[key]*incident
+	stations  map[string]data.Line
+
+	queue chan Event
+	wg    sync.WaitGroup
+}
+
+var _ position.Observer = (*Notifier)(nil)
+
+func New(c Config) (*Notifier, error) {
+	if len(c.URLs) == 0 {
+		return nil, errors.New("notify: no urls")
+	}
+	if c.NoTrainTicks <= 0 {
+		c.NoTrainTicks = defaultNoTrainTicks
+	}
+	if c.MaxGap <= 0 {
+		c.MaxGap = defaultMaxGap
+	}
+	if c.Cooldown == 0 {
+		c.Cooldown = defaultCooldown
+	}
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	n := &Notifier{
+		c:         c,
+		incidents: make(map[key]*incident),
+		stations:  make(map[string]data.Line),
+		queue:     make(chan Event, queueSize),
+	}
+
+	for _, l := range data.GetLines() {
+		n.stations[l.Name] = l.Line
+	}
+
+	n.wg.Add(1)
+	go n.send()
+
+	return n, nil
+}
+
+// Close stops accepting events and waits for the queued ones to be sent.
+func (n *Notifier) Close() {
+	close(n.queue)
+	n.wg.Wait()
+}
+
+func (n *Notifier) Observe(_ context.Context, u *position.Update) {
+	for _, l := range data.GetLines() {
+		line, ok := u.Lines[l.Name]
+		if !ok {
+			continue
+		}
+
+		down := lineDown(line)
+		n.check(u.Time, key{l.Name, KindLineDown}, down, 1,
+			"no arrival times for any station")
+
+		// an empty board is expected if the whole line is down, don't report it twice
+		n.check(u.Time, key{l.Name, KindNoTrains}, !down && noTrains(u.Positions[l.Name]), n.c.NoTrainTicks,
+			fmt.Sprintf("no trains detected for %d updates", n.c.NoTrainTicks))
+
+		i, gap := longestGap(line)
+		msg := ""
+		if i >= 0 {
+			msg = fmt.Sprintf("next train at %s in %d min", n.stationName(l.Name, i), gap)
+		}
+		n.check(u.Time, key{l.Name, KindLongGap}, gap > n.c.MaxGap, 1, msg)
+	}
+}
+
+// check advances the incident for k. active is whether the condition holds on this tick,
+// it has to hold for minTicks ticks in a row before the incident is opened.
+func (n *Notifier) check(now time.Time, k key, active bool, minTicks int, msg string) {
+	inc, ok := n.incidents[k]
+	if !ok {
+		inc = &incident{}
+		n.incidents[k] = inc
+	}
+
+	if !active {
+		if inc.open && inc.notified {
+			n.enqueue(Event{
+				Event:      EventResolved,
+				Kind:       k.kind,
+				Line:       k.line,
+				Message:    "service recovered",
+				StartedAt:  toMillis(inc.since),
+				ResolvedAt: toMillis(now),
+			})
+		}
+		inc.ticks = 0
+		inc.open = false
+		inc.notified = false
+		return
+	}
+
+	inc.ticks++
+	if inc.notified || inc.ticks < minTicks {
+		return
+	}
+
+	if !inc.open {
+		inc.open = true
+		inc.since = now
+	}
+	if !inc.lastTriggered.IsZero() && now.Sub(inc.lastTriggered) < n.c.Cooldown {
+		// still cooling down from the last one, try again on the next tick
+		return
+	}
+
+	inc.notified = true
+	inc.lastTriggered = now
+	n.enqueue(Event{
+		Event:     EventTriggered,
+		Kind:      k.kind,
+		Line:      k.line,
+		Message:   msg,
+		StartedAt: toMillis(inc.since),
+	})
+}
+
+func (n *Notifier) enqueue(e Event) {
+	select {
+	case n.queue <- e:
+	default:
+		log.Printf("error: notify: queue full, dropping %s %s %s", e.Event, e.Line, e.Kind)
+	}
+}
+
+func (n *Notifier) send() {
+	defer n.wg.Done()
+
+	for e := range n.queue {
+		e.SentAt = toMillis(time.Now())
+		body, err := json.Marshal(e)
+		if err != nil {
+			log.Printf("error: notify: marshal: %v", err)
+			continue
+		}
+
+		for _, url := range n.c.URLs {
+			var err error
+			for try := 0; try < maxTries; try++ {
+				err = n.post(url, body)
+				if err == nil {
+					break
+				}
+				time.Sleep(time.Duration(try+1) * time.Second)
+			}
+			if err != nil {
+				log.Printf("error: notify: %s: %v", url, err)
+			}
+		}
+	}
+}
+
+func (n *Notifier) post(url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("content-type", "application/json")
+	if n.c.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(n.c.Secret, body))
+	}
+
+	resp, err := n.c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (n *Notifier) stationName(line string, i int) string {
+	l := n.stations[line]
+	if i < 0 || i >= len(l) {
+		return fmt.Sprintf("#%d", i)
+	}
+	return l[i].Name
+}
+
+// Sign returns the signature header value for body. Receivers should compute the same value
+// and compare it with hmac.Equal.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func lineDown(l model.Line) bool {
+	if len(l) == 0 {
+		return false
+	}
+	for i := range l {
+		if l[i].Next != -1 {
+			return false
+		}
+	}
+	return true
+}
+
+func noTrains(p model.Position) bool {
+	for i := range p {
+		if p[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// longestGap finds the platform with the longest wait for the next train.
+// If no platform has an arrival time it returns -1, -1.
+func longestGap(l model.Line) (int, int) {
+	idx, gap := -1, -1
+	for i := range l {
+		if l[i].Next > gap {
+			idx, gap = i, l[i].Next
+		}
+	}
+	return idx, gap
+}
+
+func toMillis(t time.Time) uint64 {
+	return uint64(t.UnixNano() / 1000000)
+}
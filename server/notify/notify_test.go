@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/server/handler/position"
+)
+
+func update(t time.Time, next ...int) *position.Update {
+	l := make(model.Line, len(next))
+	for i := range next {
+		l[i].Next = next[i]
+	}
+	return &position.Update{
+		Time:      t,
+		Lines:     map[string]model.Line{"cg1": l},
+		Positions: map[string]model.Position{"cg1": l.ToPosition()},
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	const secret = "hunter2"
+	events := make(chan Event, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !hmac.Equal([]byte(r.Header.Get(SignatureHeader)), []byte(Sign(secret, body))) {
+			t.Errorf("bad signature %q", r.Header.Get(SignatureHeader))
+		}
+		var e Event
+		if err := json.Unmarshal(body, &e); err != nil {
+			t.Error(err)
+		}
+		events <- e
+	}))
+	defer srv.Close()
+
+	n, err := New(Config{
+		URLs:         []string{srv.URL},
+		Secret:       secret,
+		NoTrainTicks: 2,
+		Cooldown:     time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1600000000, 0)
+	ticks := [][]int{
+		{0, 3, 5},    // ok
+		{-1, -1, -1}, // line down
+		{-1, -1, -1}, // still down, no duplicate
+		{0, 3, 5},    // resolved
+		{-1, -1, -1}, // down again but cooling down
+		{-1, -1, -1},
+		{0, 3, 5}, // recovered but nothing to resolve
+		{2, 4, 30},
+	}
+	for i, next := range ticks {
+		n.Observe(context.Background(), update(start.Add(time.Duration(i)*time.Minute), next...))
+	}
+	n.Close()
+	close(events)
+
+	var got []Event
+	for e := range events {
+		got = append(got, e)
+	}
+
+	expected := []struct {
+		event, kind string
+		started     time.Time
+	}{
+		{EventTriggered, KindLineDown, start.Add(1 * time.Minute)},
+		{EventResolved, KindLineDown, start.Add(1 * time.Minute)},
+		{EventTriggered, KindLongGap, start.Add(7 * time.Minute)},
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %+v", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i].Event != expected[i].event || got[i].Kind != expected[i].kind || got[i].Line != "cg1" ||
+			got[i].StartedAt != toMillis(expected[i].started) {
+			t.Errorf("event %d: expected %+v, got %+v", i, expected[i], got[i])
+		}
+	}
+	if got[1].ResolvedAt != toMillis(start.Add(3*time.Minute)) {
+		t.Errorf("resolved at %d", got[1].ResolvedAt)
+	}
+}
+
+func TestNotifier_NoTrains(t *testing.T) {
+	var n Notifier
+	n.c.NoTrainTicks = 3
+	n.c.MaxGap = defaultMaxGap
+	n.incidents = make(map[key]*incident)
+	n.queue = make(chan Event, 10)
+
+	start := time.Unix(1600000000, 0)
+	for i := 0; i < 5; i++ {
+		// arrivals known but nobody at or next to a platform
+		n.Observe(context.Background(), update(start.Add(time.Duration(i)*time.Minute), 5, 7, 9))
+	}
+	close(n.queue)
+
+	var got []Event
+	for e := range n.queue {
+		got = append(got, e)
+	}
+	if len(got) != 1 || got[0].Kind != KindNoTrains || got[0].StartedAt != toMillis(start.Add(2*time.Minute)) {
+		t.Errorf("expected one no_trains event at the third tick, got %+v", got)
+	}
+}
+
+func TestNotifier_Cooldown(t *testing.T) {
+	var n Notifier
+	n.c.NoTrainTicks = defaultNoTrainTicks
+	n.c.MaxGap = defaultMaxGap
+	n.c.Cooldown = 10 * time.Minute
+	n.incidents = make(map[key]*incident)
+	n.queue = make(chan Event, 10)
+
+	start := time.Unix(1600000000, 0)
+	tick := func(minute int, next ...int) {
+		n.Observe(context.Background(), update(start.Add(time.Duration(minute)*time.Minute), next...))
+	}
+	tick(0, -1, -1, -1) // down
+	tick(1, 0, 3, 5)    // resolved
+	// down again within the cooldown, and for long after it
+	for minute := 2; minute <= 15; minute++ {
+		tick(minute, -1, -1, -1)
+	}
+	close(n.queue)
+
+	var got []Event
+	for e := range n.queue {
+		got = append(got, e)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 events, got %+v", got)
+	}
+	e := got[2]
+	if e.Event != EventTriggered || e.StartedAt != toMillis(start.Add(2*time.Minute)) {
+		t.Errorf("expected the second outage once the cooldown is over, got %+v", e)
+	}
+}
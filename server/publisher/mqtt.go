@@ -0,0 +1,203 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.lepak.sg/mrtracker-backend/data"
+	"go.lepak.sg/mrtracker-backend/server/handler/position"
+)
+
+const (
+	defaultTopicPrefix    = "mrt"
+	defaultClientID       = "mrtracker-backend"
+	defaultPublishTimeout = 5 * time.Second
+
+	statusOnline  = "online"
+	statusOffline = "offline"
+)
+
+type MQTTConfig struct {
+	// Broker URL, eg tcp://localhost:1883
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// All topics are published under this prefix, default "mrt"
+	TopicPrefix string
+	QoS         byte
+	// How long to wait for the broker to accept one tick's messages
+	PublishTimeout time.Duration
+}
+
+// MQTT publishes every update to a broker:
+//
+//	<prefix>/position/<line>   same object as one element of /v1/position
+//	<prefix>/board/dev_v1      same object as /v1/position?format=dev_v1
+//	<prefix>/arrivals/<code3>  raw arrivals for the station
+//	<prefix>/status            "online", or "offline" as the last will
+//
+// All messages are retained, so a board that connects between ticks gets the latest state immediately.
+type MQTT struct {
+	client mqtt.Client
+	// completes once the first connection is made, Close waits on it so it doesn't disconnect
+	// while the client is still setting up the connection
+	connected mqtt.Token
+	prefix    string
+	qos       byte
+	timeout   time.Duration
+
+	// station name -> lowercase code3
+	stationTopics map[string]string
+}
+
+var _ position.Observer = (*MQTT)(nil)
+
+type positionMessage struct {
+	Line        string `json:"line"`
+	Positions   string `json:"positions"`
+	LastUpdated uint64 `json:"last_updated"`
+}
+
+type boardMessage struct {
+	Data        []string `json:"data"`
+	LastUpdated uint64   `json:"last_updated"`
+}
+
+// NewMQTT connects to the broker. Connecting is retried in the background,
+// so this only fails if the config is bad.
+func NewMQTT(c MQTTConfig) (*MQTT, error) {
+	if c.Broker == "" {
+		return nil, errors.New("mqtt: no broker")
+	}
+	if c.ClientID == "" {
+		c.ClientID = defaultClientID
+	}
+	if c.TopicPrefix == "" {
+		c.TopicPrefix = defaultTopicPrefix
+	}
+	if c.QoS > 2 {
+		return nil, fmt.Errorf("mqtt: invalid qos %d", c.QoS)
+	}
+	if c.PublishTimeout <= 0 {
+		c.PublishTimeout = defaultPublishTimeout
+	}
+
+	m := &MQTT{
+		prefix:        strings.TrimSuffix(c.TopicPrefix, "/"),
+		qos:           c.QoS,
+		timeout:       c.PublishTimeout,
+		stationTopics: make(map[string]string),
+	}
+
+	for _, l := range data.GetLines() {
+		for _, s := range l.Line {
+			m.stationTopics[s.Name] = strings.ToLower(s.Code3)
+		}
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(c.Broker).
+		SetClientID(c.ClientID).
+		SetUsername(c.Username).
+		SetPassword(c.Password).
+		SetWill(m.topic("status"), statusOffline, c.QoS, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(func(client mqtt.Client) {
+			log.Printf("mqtt: connected to %s", c.Broker)
+			// don't wait on the token here, it would block the client's goroutine
+			client.Publish(m.topic("status"), m.qos, true, statusOnline)
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("mqtt: connection lost: %v", err)
+		})
+
+	m.client = mqtt.NewClient(opts)
+	m.connected = m.client.Connect() // with SetConnectRetry this token only completes once connected
+
+	return m, nil
+}
+
+func (m *MQTT) topic(parts ...string) string {
+	return m.prefix + "/" + strings.Join(parts, "/")
+}
+
+// Observe publishes one update. Nothing is queued if the broker is unreachable,
+// the next tick will overwrite the retained messages anyway.
+func (m *MQTT) Observe(ctx context.Context, u *position.Update) {
+	if !m.client.IsConnectionOpen() {
+		return
+	}
+
+	lastUpdated := uint64(u.Time.UnixNano() / 1000000)
+	var tokens []mqtt.Token
+
+	publish := func(topic string, v interface{}) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			log.Printf("error: mqtt: marshal for %s: %v", topic, err)
+			return
+		}
+		tokens = append(tokens, m.client.Publish(topic, m.qos, true, b))
+	}
+
+	for _, l := range data.GetLines() {
+		p, ok := u.Positions[l.Name]
+		if !ok {
+			continue
+		}
+		publish(m.topic("position", l.Name), positionMessage{
+			Line:        l.Name,
+			Positions:   p.ToString(),
+			LastUpdated: lastUpdated,
+		})
+	}
+
+	publish(m.topic("board", "dev_v1"), boardMessage{
+		Data:        u.DevV1,
+		LastUpdated: lastUpdated,
+	})
+
+	for name, r := range u.Results {
+		code3, ok := m.stationTopics[name]
+		if !ok {
+			continue
+		}
+		publish(m.topic("arrivals", code3), r)
+	}
+
+	timeout := time.NewTimer(m.timeout)
+	defer timeout.Stop()
+
+	for _, t := range tokens {
+		select {
+		case <-t.Done():
+			if t.Error() != nil {
+				log.Printf("error: mqtt: publish: %v", t.Error())
+			}
+		case <-timeout.C:
+			log.Printf("error: mqtt: publish timed out")
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Close marks the publisher offline and disconnects.
+// The will is not sent on a clean disconnect, so publish the offline status ourselves.
+func (m *MQTT) Close() {
+	m.connected.WaitTimeout(m.timeout)
+	if m.client.IsConnectionOpen() {
+		t := m.client.Publish(m.topic("status"), m.qos, true, statusOffline)
+		t.WaitTimeout(m.timeout)
+	}
+	m.client.Disconnect(250)
+}
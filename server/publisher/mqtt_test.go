@@ -0,0 +1,127 @@
+package publisher
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"go.lepak.sg/mrtracker-backend/model"
+	"go.lepak.sg/mrtracker-backend/server/handler/position"
+	"go.lepak.sg/mrtracker-backend/smrt"
+)
+
+// fakeBroker accepts one client, acks its connect and forwards everything it publishes
+func fakeBroker(t *testing.T) (string, <-chan *packets.ConnectPacket, <-chan *packets.PublishPacket) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	connCh := make(chan *packets.ConnectPacket, 1)
+	pubCh := make(chan *packets.PublishPacket, 100)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			cp, err := packets.ReadPacket(conn)
+			if err != nil {
+				return
+			}
+			switch p := cp.(type) {
+			case *packets.ConnectPacket:
+				connCh <- p
+				ack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+				if ack.Write(conn) != nil {
+					return
+				}
+			case *packets.PublishPacket:
+				pubCh <- p
+			case *packets.PingreqPacket:
+				resp := packets.NewControlPacket(packets.Pingresp)
+				if resp.Write(conn) != nil {
+					return
+				}
+			case *packets.DisconnectPacket:
+				return
+			}
+		}
+	}()
+
+	return "tcp://" + ln.Addr().String(), connCh, pubCh
+}
+
+func waitPublish(t *testing.T, ch <-chan *packets.PublishPacket) *packets.PublishPacket {
+	select {
+	case p := <-ch:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for publish")
+		return nil
+	}
+}
+
+func TestMQTT_Observe(t *testing.T) {
+	broker, connCh, pubCh := fakeBroker(t)
+
+	m, err := NewMQTT(MQTTConfig{Broker: broker, TopicPrefix: "test/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	select {
+	case cp := <-connCh:
+		if !cp.WillFlag || !cp.WillRetain || cp.WillTopic != "test/status" || string(cp.WillMessage) != statusOffline {
+			t.Errorf("bad will: flag=%t retain=%t topic=%q msg=%q",
+				cp.WillFlag, cp.WillRetain, cp.WillTopic, cp.WillMessage)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for connect")
+	}
+
+	p := waitPublish(t, pubCh)
+	if p.TopicName != "test/status" || string(p.Payload) != statusOnline || !p.Retain {
+		t.Errorf("expected retained online status, got %q=%q retain=%t", p.TopicName, p.Payload, p.Retain)
+	}
+
+	m.Observe(context.Background(), &position.Update{
+		Time:      time.Unix(1600000000, 0),
+		Positions: map[string]model.Position{"ew1": {true, false, true}},
+		DevV1:     []string{"00", "01", "02"},
+		Results: map[string]smrt.Result{
+			"Tanah Merah": {{Mrt: "Tanah Merah", PlatformID: "TNM_B", NextTrainArr: "Arr"}},
+			"Nowhere":     {{Mrt: "Nowhere", PlatformID: "NOW_A"}},
+		},
+	})
+
+	expected := map[string]string{
+		"test/position/ew1": `{"line":"ew1","positions":"*_*","last_updated":1600000000000}`,
+		"test/board/dev_v1": `{"data":["00","01","02"],"last_updated":1600000000000}`,
+		"test/arrivals/tnm": `[{"mrt":"Tanah Merah","next_train_arr":"Arr","platform_ID":"TNM_B"}]`,
+	}
+
+	n := len(expected)
+	for i := 0; i < n; i++ {
+		p := waitPublish(t, pubCh)
+		want, ok := expected[p.TopicName]
+		if !ok {
+			t.Errorf("unexpected topic %q", p.TopicName)
+			continue
+		}
+		if string(p.Payload) != want {
+			t.Errorf("%s: expected %s, got %s", p.TopicName, want, p.Payload)
+		}
+		if !p.Retain {
+			t.Errorf("%s: not retained", p.TopicName)
+		}
+		delete(expected, p.TopicName)
+	}
+}
@@ -8,8 +8,19 @@ import (
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.lepak.sg/mrtracker-backend/lta"
+	"go.lepak.sg/mrtracker-backend/provider"
+	"go.lepak.sg/mrtracker-backend/recorder"
+	"go.lepak.sg/mrtracker-backend/sbs"
+	"go.lepak.sg/mrtracker-backend/server/handler/alerts"
+	"go.lepak.sg/mrtracker-backend/server/handler/history"
+	"go.lepak.sg/mrtracker-backend/server/handler/lines"
 	"go.lepak.sg/mrtracker-backend/server/handler/position"
+	"go.lepak.sg/mrtracker-backend/server/handler/stations"
 	"go.lepak.sg/mrtracker-backend/server/handler/status"
+	"go.lepak.sg/mrtracker-backend/server/notify"
+	"go.lepak.sg/mrtracker-backend/server/publisher"
+	"go.lepak.sg/mrtracker-backend/smrt"
 )
 
 /*
@@ -19,11 +30,84 @@ TODO:
  - possibly scrape alternative apis (eg the sg busleh one, do they use their own proxy?)
 */
 
+type Config struct {
+	Addr     string
+	PrivAddr string
+	// If not nil, publish every update to this MQTT broker
+	MQTT *publisher.MQTTConfig
+	// If not nil, send webhooks when service anomalies start and end
+	Notify *notify.Config
+	// If DSN is not empty, historical data is read from the recorder store
+	StoreBackend string
+	DSN          string
+	// Serve the typical day built from recordings instead of live data. Requires DSN.
+	Typical bool
+	// If not nil, play back recordings instead of serving live data. Requires DSN.
+	Replay *position.ReplayConfig
+	// If not nil, arrivals for SBS Transit lines come from here instead of smrt
+	SBS *sbs.Provider
+	// If not nil, official service alerts are included in status and live positions
+	LTA *lta.Client
+}
+
+// sbsPrefixes are the station code prefixes of the lines SBS Transit runs, including the Sengkang
+// and Punggol LRT
+var sbsPrefixes = []string{"NE", "DT", "CC", "CE", "SE", "SW", "PE", "PW"}
+
 // StartHttp starts the http server. It blocks until the context is cancelled, then it will shut down the server.
 // It will also start a secondary server to serve prometheus metrics. We could attach pprof, expvar etc to it.
 // Obviously, in the reverse proxy config, only route requests to the first addr and not the second
-func StartHttp(ctx context.Context, addr string, privAddr string) {
+func StartHttp(ctx context.Context, c Config) {
 	wg := &sync.WaitGroup{}
+
+	var store recorder.Store
+	alertsConfig := alerts.Config{}
+	if c.DSN != "" {
+		var err error
+		store, err = recorder.Open(c.StoreBackend, c.DSN)
+		if err != nil {
+			log.Fatalf("recorder store: %v", err)
+		}
+		defer store.Close()
+
+		// the queries need the columns of every migration, even if the recorder never ran
+		migrated, err := store.Migrate(ctx)
+		if err != nil {
+			log.Fatalf("recorder store: migrating: %v", err)
+		}
+		if migrated > 0 {
+			log.Printf("recorder store: applied %d migrations", migrated)
+		}
+		alertsConfig.History = store
+	}
+
+	linesHandler := lines.New()
+	alertsHandler := alerts.New(alertsConfig)
+	observers := []position.Observer{linesHandler, alertsHandler}
+
+	if c.MQTT != nil && c.Replay != nil {
+		// retained messages would overwrite the live state for every subscriber
+		log.Print("not publishing to mqtt for a replay")
+	} else if c.MQTT != nil {
+		pub, err := publisher.NewMQTT(*c.MQTT)
+		if err != nil {
+			log.Fatalf("mqtt publisher: %v", err)
+		}
+		defer pub.Close()
+		observers = append(observers, pub)
+	}
+
+	if c.Notify != nil && c.Replay != nil {
+		log.Print("not sending webhooks for a replay")
+	} else if c.Notify != nil {
+		n, err := notify.New(*c.Notify)
+		if err != nil {
+			log.Fatalf("notifier: %v", err)
+		}
+		defer n.Close()
+		observers = append(observers, n)
+	}
+
 	privMux := http.NewServeMux()
 	privMux.Handle("/metrics", promhttp.Handler())
 
@@ -34,7 +118,7 @@ func StartHttp(ctx context.Context, addr string, privAddr string) {
 	privMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 
 	privSrv := &http.Server{
-		Addr:    privAddr,
+		Addr:    c.PrivAddr,
 		Handler: privMux,
 	}
 
@@ -48,16 +132,59 @@ func StartHttp(ctx context.Context, addr string, privAddr string) {
 	}(wg, privSrv)
 
 	mux := http.NewServeMux()
-	mux.Handle("/v1/position", position.MustNew(position.NewParam{
+	positionParam := position.NewParam{
 		Ctx:            ctx,
 		UpdateInterval: 0, // default
 		Strategy:       position.UpdateLive,
 		NumWorkers:     10,
 		MaxTries:       100,
-	}))
-	mux.Handle("/v1/status", status.Handler{})
+		Observers:      observers,
+	}
+	if c.SBS != nil {
+		route := provider.Route{
+			Default:  smrt.Provider{NumWorkers: positionParam.NumWorkers, MaxTries: positionParam.MaxTries},
+			Prefixes: make(map[string]provider.Provider),
+		}
+		for _, prefix := range sbsPrefixes {
+			route.Prefixes[prefix] = c.SBS
+		}
+		positionParam.Provider = route
+	}
+	if store != nil {
+		positionParam.Typical = store
+		positionParam.Recorded = store
+	}
+	if c.Typical {
+		positionParam.Strategy = position.UpdateRecorded
+	}
+	if c.Replay != nil {
+		if store == nil {
+			log.Fatal("replay needs a recorder store")
+		}
+		positionParam.Strategy = position.UpdateReplay
+		positionParam.Replay = c.Replay
+	}
+	var serviceAlerts *lta.Cache
+	if c.LTA != nil {
+		serviceAlerts = &lta.Cache{Client: c.LTA}
+		positionParam.ServiceAlerts = serviceAlerts
+	}
+	positionHandler := position.MustNew(positionParam)
+	mux.Handle("/v1/position", positionHandler)
+	mux.Handle("/v1/position.geojson", positionHandler.GeoJSON())
+	mux.Handle(lines.Prefix, linesHandler)
+	mux.Handle(stations.Prefix, stations.Handler{})
+	mux.Handle("/v1/alerts", alertsHandler)
+	if store != nil {
+		mux.Handle("/v1/history/position", history.New(store))
+	}
+	statusHandler := status.Handler{}
+	if serviceAlerts != nil {
+		statusHandler.ServiceAlerts = serviceAlerts
+	}
+	mux.Handle("/v1/status", statusHandler)
 	srv := &http.Server{
-		Addr:    addr,
+		Addr:    c.Addr,
 		Handler: mux,
 	}
 	wg.Add(1)
@@ -82,5 +209,8 @@ func StartHttp(ctx context.Context, addr string, privAddr string) {
 		log.Printf("error shutting down priv server: %v", err)
 	}
 
+	// observers are closed after this returns, so make sure nothing is still feeding them
+	positionHandler.Stop()
+
 	wg.Wait()
 }
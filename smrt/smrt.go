@@ -42,8 +42,10 @@ func (p *NextTrains) Valid() bool {
 
 type Result []NextTrains
 
+// ToModel picks each station's platform of src out of r. A station or platform with no result,
+// eg when its provider failed, is unknown rather than a train at the platform.
 func ToModel(r map[string]Result, src data.Line) model.Line {
-	out := make(model.Line, len(src))
+	out := unknownLine(len(src))
 
 	for i := 0; i < len(src); i++ {
 		results, ok := r[src[i].Name]
@@ -55,14 +57,8 @@ func ToModel(r map[string]Result, src data.Line) model.Line {
 		for _, r := range results {
 			if r.PlatformID == platformID {
 				out[i].Dest = r.NextTrainDestination
-
-				if r.NextTrainArr == "Arr" {
-					out[i].Next = 0
-				} else if nextInt, err := strconv.Atoi(r.NextTrainArr); err == nil {
-					out[i].Next = nextInt
-				} else {
-					out[i].Next = -1
-				}
+				out[i].Next = parseArr(r.NextTrainArr)
+				out[i].Subseq = parseArr(r.SubseqTrainArr)
 				break
 			}
 		}
@@ -72,7 +68,7 @@ func ToModel(r map[string]Result, src data.Line) model.Line {
 }
 
 func ToModelPlatform(r map[string]*NextTrains, src data.Line) model.Line {
-	out := make(model.Line, len(src))
+	out := unknownLine(len(src))
 
 	for i := 0; i < len(src); i++ {
 		r, ok := r[src[i].Platform]
@@ -80,15 +76,28 @@ func ToModelPlatform(r map[string]*NextTrains, src data.Line) model.Line {
 			continue
 		}
 		out[i].Dest = r.NextTrainDestination
-
-		if r.NextTrainArr == "Arr" {
-			out[i].Next = 0
-		} else if nextInt, err := strconv.Atoi(r.NextTrainArr); err == nil {
-			out[i].Next = nextInt
-		} else {
-			out[i].Next = -1
-		}
+		out[i].Next = parseArr(r.NextTrainArr)
+		out[i].Subseq = parseArr(r.SubseqTrainArr)
 	}
 
 	return out
 }
+
+func unknownLine(n int) model.Line {
+	out := make(model.Line, n)
+	for i := range out {
+		out[i] = model.Platform{Next: -1, Subseq: -1}
+	}
+	return out
+}
+
+// parseArr converts an arrival time string to minutes, where "Arr" is 0 and anything unrecognised is -1
+func parseArr(s string) int {
+	if s == "Arr" {
+		return 0
+	} else if n, err := strconv.Atoi(s); err == nil {
+		return n
+	} else {
+		return -1
+	}
+}
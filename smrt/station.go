@@ -103,6 +103,11 @@ retryLoop:
 	}
 }
 
+type stationResult struct {
+	station string
+	result  Result
+}
+
 // GetN retrieves the station arrival data from SMRT's API.
 // numWorkers is the number of worker goroutines to start (and therefore also the maximum number
 // of requests that can be made concurrently).
@@ -146,7 +151,7 @@ func GetN(ctx context.Context, numWorkers, maxTries int, stations ...string) (ma
 						cancel()
 						resultCh <- err
 					} else {
-						resultCh <- result
+						resultCh <- stationResult{station, result}
 					}
 
 				}
@@ -178,9 +183,9 @@ func GetN(ctx context.Context, numWorkers, maxTries int, stations ...string) (ma
 		// closed when all workers have exited
 		for re := range resultCh {
 			switch r := re.(type) {
-			case Result:
-				// assumes that response mrt field is the same as input station param
-				out[r[0].Mrt] = r
+			case stationResult:
+				// keyed by what was asked for, the mrt field in the response doesn't always match
+				out[r.station] = r.result
 			case error:
 				err = r
 			default:
@@ -195,3 +200,13 @@ func GetN(ctx context.Context, numWorkers, maxTries int, stations ...string) (ma
 
 	return out, totalTries, err
 }
+
+// Provider gets arrivals from the smrt API with GetN
+type Provider struct {
+	NumWorkers int
+	MaxTries   int
+}
+
+func (p Provider) Arrivals(ctx context.Context, stations []string) (map[string]Result, int64, error) {
+	return GetN(ctx, p.NumWorkers, p.MaxTries, stations...)
+}